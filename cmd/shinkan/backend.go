@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"shinkan-rebirth/internal/auth"
+	"shinkan-rebirth/internal/checker"
+	"shinkan-rebirth/internal/config"
+	"shinkan-rebirth/internal/discovery"
+	"shinkan-rebirth/internal/events"
+	"shinkan-rebirth/internal/models"
+	"shinkan-rebirth/internal/notifier"
+	"shinkan-rebirth/internal/stats"
+	"shinkan-rebirth/internal/storage"
+	"shinkan-rebirth/internal/torrent"
+)
+
+// backend is what the CLI commands run against: the data files directly, or
+// a running instance's HTTP API
+type backend interface {
+	ListFeeds() ([]models.Feed, error)
+	GetFeed(id string) (*models.Feed, error)
+	AddFeed(feed models.Feed) (models.Feed, error)
+	UpdateFeed(id string, updates map[string]interface{}) (*models.Feed, error)
+	DeleteFeed(id string) error
+	ListSeries() ([]models.Series, error)
+	UpdateSeries(id string, updates map[string]interface{}) (*models.Series, error)
+	CheckFeed(ctx context.Context, id string) (*models.Feed, error)
+	CheckAll(ctx context.Context) (models.CheckJob, error)
+	DryRun(ctx context.Context, id string) ([]models.CheckPreview, error)
+	TestFeed(ctx context.Context, id string) (map[string]interface{}, error)
+	Import(feeds []models.Feed, opts storage.ImportOptions) (storage.ImportReport, error)
+	Stats() (models.Stats, error)
+	Close()
+}
+
+// localBackend works on the data files. Feeds of every user are visible;
+// new feeds belong to the default user.
+type localBackend struct {
+	cfg       *config.Config
+	storage   *storage.Storage
+	discovery *discovery.Discoverer
+	bus       *events.Bus
+	handoff   *torrent.Handoff
+	notifier  *notifier.Notifier
+	checker   *checker.Checker
+}
+
+func newLocalBackend(cfg *config.Config) (*localBackend, error) {
+	if err := migrateDataFiles(cfg); err != nil {
+		return nil, err
+	}
+	bus := events.New()
+	return &localBackend{
+		cfg:       cfg,
+		storage:   storage.New(cfg.MangaDataFile, cfg.AnimeDataFile),
+		discovery: discovery.New(cfg.FetchTimeout),
+		bus:       bus,
+		handoff:   newTorrentHandoff(cfg, bus),
+	}, nil
+}
+
+// check sets up the checker on first use, only commands that fetch feeds
+// need notification settings
+func (b *localBackend) check() (*checker.Checker, error) {
+	if b.checker != nil {
+		return b.checker, nil
+	}
+	if err := b.cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	authManager, err := auth.New(b.cfg.AdminPassword, b.cfg.APIKeysFile, b.cfg.UsersFile, b.cfg.SessionTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule, err := newSchedule(b.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	b.notifier = notifier.New(b.cfg.GotifyServer, b.cfg.GotifyToken, b.cfg.DiscordToken, b.cfg.DiscordChannelID, b.cfg.NotifyTimeout, b.cfg.DedupeWindow, b.cfg.DedupeFile, schedule, b.bus, authManager.Targets)
+	b.checker = checker.New(b.storage, b.notifier, b.bus, b.cfg.FetchTimeout, b.cfg.MangaDexAPI)
+	b.bus.Handle(b.notifier.HandleEvent)
+	b.bus.Handle(b.handoff.HandleEvent)
+	return b.checker, nil
+}
+
+func (b *localBackend) ListFeeds() ([]models.Feed, error) {
+	return b.storage.GetFeeds()
+}
+
+func (b *localBackend) GetFeed(id string) (*models.Feed, error) {
+	feeds, err := b.storage.GetFeeds()
+	if err != nil {
+		return nil, err
+	}
+	for i := range feeds {
+		if feeds[i].ID == id {
+			return &feeds[i], nil
+		}
+	}
+	return nil, storage.ErrFeedNotFound
+}
+
+// resolveFeedURL finds the feed for a page URL like the API does, keeping
+// URLs that can't be fetched right now
+func (b *localBackend) resolveFeedURL(rssURL string) (string, error) {
+	resolved, _, err := b.discovery.Resolve(context.Background(), rssURL)
+	if errors.Is(err, discovery.ErrNoFeed) {
+		return "", err
+	}
+	return resolved, nil
+}
+
+func (b *localBackend) AddFeed(feed models.Feed) (models.Feed, error) {
+	if err := b.handoff.Validate(feed.Torrent); err != nil {
+		return models.Feed{}, err
+	}
+	if feed.SourceKind() == models.SourceRSS {
+		rssURL, err := b.resolveFeedURL(feed.RSSUrl)
+		if err != nil {
+			return models.Feed{}, err
+		}
+		feed.RSSUrl = rssURL
+	}
+	return b.storage.AddFeed(feed)
+}
+
+func (b *localBackend) UpdateFeed(id string, updates map[string]interface{}) (*models.Feed, error) {
+	feed, err := b.GetFeed(id)
+	if err != nil {
+		return nil, err
+	}
+	if source, ok := updates["source"].(*models.Source); ok {
+		feed.Source = source
+	}
+	if handoff, ok := updates["torrent"].(*models.TorrentHandoff); ok {
+		if err := b.handoff.Validate(handoff); err != nil {
+			return nil, err
+		}
+	}
+
+	if u, ok := updates["rssUrl"].(string); ok && feed.SourceKind() == models.SourceRSS {
+		rssURL, err := b.resolveFeedURL(u)
+		if err != nil {
+			return nil, err
+		}
+		updates["rssUrl"] = rssURL
+	}
+	return b.storage.UpdateFeed(id, updates)
+}
+
+func (b *localBackend) DeleteFeed(id string) error {
+	feed, err := b.GetFeed(id)
+	if err != nil {
+		return err
+	}
+	return b.storage.DeleteFeed(feed.OwnerID, id)
+}
+
+func (b *localBackend) ListSeries() ([]models.Series, error) {
+	return b.storage.GetAllSeries()
+}
+
+func (b *localBackend) UpdateSeries(id string, updates map[string]interface{}) (*models.Series, error) {
+	series, err := b.storage.GetAllSeries()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range series {
+		if s.ID == id {
+			return b.storage.UpdateSeries(s.OwnerID, id, updates)
+		}
+	}
+	return nil, storage.ErrSeriesNotFound
+}
+
+func (b *localBackend) CheckFeed(ctx context.Context, id string) (*models.Feed, error) {
+	check, err := b.check()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := b.GetFeed(id); err != nil {
+		return nil, err
+	}
+	if err := check.CheckOne(ctx, id, checker.TriggerCLI); err != nil {
+		return nil, err
+	}
+	return b.GetFeed(id)
+}
+
+func (b *localBackend) CheckAll(ctx context.Context) (models.CheckJob, error) {
+	check, err := b.check()
+	if err != nil {
+		return models.CheckJob{}, err
+	}
+
+	check.CheckAll(ctx, checker.TriggerCLI)
+	job, ok := check.CheckStatus()
+	if !ok {
+		return models.CheckJob{}, fmt.Errorf("check did not run")
+	}
+	return job, nil
+}
+
+// DryRun previews a check of one feed, or of all feeds if id is empty
+func (b *localBackend) DryRun(ctx context.Context, id string) ([]models.CheckPreview, error) {
+	check, err := b.check()
+	if err != nil {
+		return nil, err
+	}
+
+	if id == "" {
+		feeds, err := b.storage.GetFeeds()
+		if err != nil {
+			return nil, err
+		}
+		return check.CheckAllDryRun(ctx, feeds)
+	}
+
+	feed, err := b.GetFeed(id)
+	if err != nil {
+		return nil, err
+	}
+	preview, err := check.CheckFeedDryRun(ctx, *feed, 3)
+	if err != nil {
+		return nil, err
+	}
+	return []models.CheckPreview{preview}, nil
+}
+
+func (b *localBackend) TestFeed(ctx context.Context, id string) (map[string]interface{}, error) {
+	check, err := b.check()
+	if err != nil {
+		return nil, err
+	}
+
+	feed, err := b.GetFeed(id)
+	if err != nil {
+		return nil, err
+	}
+	return check.TestFeed(ctx, feed.OwnerID, id)
+}
+
+func (b *localBackend) Import(feeds []models.Feed, opts storage.ImportOptions) (storage.ImportReport, error) {
+	opts.Validate = b.validateFeedSettings
+	return b.storage.Import(models.DefaultUserID, feeds, opts)
+}
+
+// validateFeedSettings refuses imported feeds the API would refuse to add
+func (b *localBackend) validateFeedSettings(feed models.Feed) error {
+	if err := checker.ValidateSource(feed.Source); err != nil {
+		return err
+	}
+	if err := b.handoff.Validate(feed.Torrent); err != nil {
+		return err
+	}
+	return checker.ValidatePreferences(feed.Preferences)
+}
+
+// Stats reads the counters last saved by the daemon
+func (b *localBackend) Stats() (models.Stats, error) {
+	collector, err := stats.New(b.cfg.StatsFile)
+	if err != nil {
+		return models.Stats{}, err
+	}
+	feeds, err := b.storage.GetFeeds()
+	if err != nil {
+		return models.Stats{}, err
+	}
+	return stats.Summarize(collector.Get(), feeds), nil
+}
+
+// Close delivers the held notifications that came due, as there is no
+// daemon to do it for one-off commands
+func (b *localBackend) Close() {
+	if b.notifier != nil {
+		b.notifier.Flush(context.Background())
+		b.notifier.Close()
+	}
+}
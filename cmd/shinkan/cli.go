@@ -0,0 +1,862 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"shinkan-rebirth/internal/auth"
+	"shinkan-rebirth/internal/checker"
+	"shinkan-rebirth/internal/config"
+	"shinkan-rebirth/internal/migrations"
+	"shinkan-rebirth/internal/models"
+	"shinkan-rebirth/internal/opml"
+	"shinkan-rebirth/internal/storage"
+
+	"github.com/robfig/cron/v3"
+)
+
+const usage = `Usage: shinkan [command]
+
+Without a command the daemon is started.
+
+Commands:
+  feeds list [--type manga|anime] [--category name]
+  feeds add --name name --url url [--type manga|anime] [--category name] [--anilist url] [--search text] [--source json] [--torrent json] [--preferences json] [--series id]
+  feeds rm <id>
+  feeds edit <id> [--name name] [--url url] [--type manga|anime] [--category name] [--anilist url] [--search text] [--source json] [--torrent json] [--preferences json] [--series id]
+  series list [--status status] [--category name]
+  series edit <id> [--title title] [--aliases a,b] [--anilist url] [--cover url] [--status status] [--category name] [--progress n]
+  check [--feed id] [--dry-run]
+                              Check all feeds, or a single one
+  test <id>                   Send a test notification for a feed
+  import <file> [--strategy skip|overwrite|merge|keep_state] [--preserve-state] [--dry-run]
+  export <file|-> [--format json|opml]
+  stats                       Show checker statistics
+  config validate             Check the configuration and data files
+  migrate [--dry-run]         Upgrade the data files to the current schema
+
+Common flags:
+  --server url     Use the HTTP API of a running instance (env SHINKAN_SERVER)
+  --api-key key    API key for --server (env SHINKAN_API_KEY)
+  --json           Print JSON instead of tables
+
+Without --server commands work on the data files directly. Prefer --server
+while the daemon is running, so both don't write the files at once.
+`
+
+// cliOptions are the flags shared by every command
+type cliOptions struct {
+	server string
+	apiKey string
+	json   bool
+}
+
+func (o *cliOptions) register(flags *flag.FlagSet) {
+	flags.StringVar(&o.server, "server", os.Getenv("SHINKAN_SERVER"), "URL of a running instance to use instead of the data files")
+	flags.StringVar(&o.apiKey, "api-key", os.Getenv("SHINKAN_API_KEY"), "API key for --server")
+	flags.BoolVar(&o.json, "json", false, "print JSON instead of tables")
+}
+
+// backend opens the data files, or connects to the server if one is set
+func (o *cliOptions) backend() (backend, error) {
+	if o.server != "" {
+		return newRemoteBackend(o.server, o.apiKey), nil
+	}
+	return newLocalBackend(config.Read())
+}
+
+// newFlags creates a flag set for a command with the common flags
+func newFlags(name string, opts *cliOptions) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	opts.register(flags)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+	}
+	return flags
+}
+
+// parseFlags parses flags given before, between or after the positional
+// arguments and returns the positional ones
+func parseFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+// runCLI runs a command, returning false if name isn't one
+func runCLI(name string, args []string) (int, bool) {
+	commands := map[string]func([]string) int{
+		"feeds":  runFeeds,
+		"series": runSeries,
+		"check":  runCheck,
+		"test":   runTest,
+		"import": runImport,
+		"export": runExport,
+		"stats":  runStats,
+		"config": runConfig,
+	}
+
+	run, ok := commands[name]
+	if !ok {
+		return 0, false
+	}
+	return run(args), true
+}
+
+// commandContext is cancelled on interrupt
+func commandContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+func fail(err error) int {
+	fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+	return 1
+}
+
+func usageError(format string, args ...interface{}) int {
+	fmt.Fprintf(os.Stderr, format+"\n\n", args...)
+	fmt.Fprint(os.Stderr, usage)
+	return 2
+}
+
+func printJSON(v interface{}) int {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return fail(err)
+	}
+	return 0
+}
+
+func valueOr(s *string, fallback string) string {
+	if s == nil || *s == "" {
+		return fallback
+	}
+	return *s
+}
+
+func printFeeds(feeds []models.Feed) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTYPE\tNAME\tCATEGORY\tLAST RELEASE\tSTATUS")
+	for _, feed := range feeds {
+		status := "ok"
+		if feed.LastChecked == nil {
+			status = "never checked"
+		} else if feed.FailCount > 0 {
+			status = fmt.Sprintf("failing (%s, %dx)", feed.ErrorKind, feed.FailCount)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", feed.ID, feed.Type, feed.Name, feed.Category, valueOr(feed.LastChapter, "-"), status)
+	}
+	w.Flush()
+}
+
+// parseSource reads the --source flag, a JSON source configuration like
+// {"kind": "html", "html": {"item": ".chapter"}}. Empty means RSS.
+func parseSource(raw string) (*models.Source, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var source models.Source
+	if err := json.Unmarshal([]byte(raw), &source); err != nil {
+		return nil, fmt.Errorf("invalid --source: %w", err)
+	}
+	if err := checker.ValidateSource(&source); err != nil {
+		return nil, fmt.Errorf("invalid --source: %w", err)
+	}
+	return &source, nil
+}
+
+// parseTorrent reads the --torrent flag, hand-off settings like
+// {"client": "qbittorrent", "savePath": "/downloads/anime"}. Empty turns
+// hand-off off.
+func parseTorrent(raw string) (*models.TorrentHandoff, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var handoff models.TorrentHandoff
+	if err := json.Unmarshal([]byte(raw), &handoff); err != nil {
+		return nil, fmt.Errorf("invalid --torrent: %w", err)
+	}
+	return &handoff, nil
+}
+
+// parsePreferences reads the --preferences flag, release preferences like
+// {"groups": ["SubsPlease"], "resolution": "1080p", "gracePeriod": "2h"}.
+// Empty turns them off.
+func parsePreferences(raw string) (*models.ReleasePreferences, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var prefs models.ReleasePreferences
+	if err := json.Unmarshal([]byte(raw), &prefs); err != nil {
+		return nil, fmt.Errorf("invalid --preferences: %w", err)
+	}
+	if err := checker.ValidatePreferences(&prefs); err != nil {
+		return nil, fmt.Errorf("invalid --preferences: %w", err)
+	}
+	return &prefs, nil
+}
+
+func validFeedType(feedType string) bool {
+	return feedType == string(models.FeedTypeManga) || feedType == string(models.FeedTypeAnime)
+}
+
+// runFeeds implements `shinkan feeds list|add|rm|edit`
+func runFeeds(args []string) int {
+	if len(args) == 0 {
+		return usageError("Missing feeds command")
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		return runFeedsList(args[1:])
+	case "add":
+		return runFeedsAdd(args[1:])
+	case "rm", "remove":
+		return runFeedsRemove(args[1:])
+	case "edit":
+		return runFeedsEdit(args[1:])
+	default:
+		return usageError("Unknown feeds command %q", args[0])
+	}
+}
+
+func runFeedsList(args []string) int {
+	var opts cliOptions
+	flags := newFlags("feeds list", &opts)
+	feedType := flags.String("type", "", "only list manga or anime feeds")
+	category := flags.String("category", "", "only list feeds in this category")
+	if _, err := parseFlags(flags, args); err != nil {
+		return 2
+	}
+
+	b, err := opts.backend()
+	if err != nil {
+		return fail(err)
+	}
+	defer b.Close()
+
+	feeds, err := b.ListFeeds()
+	if err != nil {
+		return fail(err)
+	}
+
+	filtered := make([]models.Feed, 0, len(feeds))
+	for _, feed := range feeds {
+		if *feedType != "" && string(feed.Type) != *feedType {
+			continue
+		}
+		if *category != "" && !strings.EqualFold(feed.Category, *category) {
+			continue
+		}
+		filtered = append(filtered, feed)
+	}
+
+	if opts.json {
+		return printJSON(filtered)
+	}
+	printFeeds(filtered)
+	return 0
+}
+
+func runFeedsAdd(args []string) int {
+	var opts cliOptions
+	flags := newFlags("feeds add", &opts)
+	name := flags.String("name", "", "feed name")
+	url := flags.String("url", "", "RSS URL")
+	feedType := flags.String("type", string(models.FeedTypeManga), "manga or anime")
+	category := flags.String("category", "", "category")
+	anilist := flags.String("anilist", "", "AniList URL")
+	search := flags.String("search", "", "only track items containing this text (anime)")
+	sourceFlag := flags.String("source", "", "source settings as JSON, for sites without RSS")
+	torrentFlag := flags.String("torrent", "", "torrent client hand-off settings as JSON")
+	prefsFlag := flags.String("preferences", "", "release group and quality preferences as JSON (anime)")
+	series := flags.String("series", "", "series ID shared with other feeds of the same series")
+	if _, err := parseFlags(flags, args); err != nil {
+		return 2
+	}
+
+	if *name == "" || *url == "" {
+		return usageError("--name and --url are required")
+	}
+	if !validFeedType(*feedType) {
+		return usageError("--type must be manga or anime")
+	}
+	source, err := parseSource(*sourceFlag)
+	if err != nil {
+		return usageError("%v", err)
+	}
+	handoff, err := parseTorrent(*torrentFlag)
+	if err != nil {
+		return usageError("%v", err)
+	}
+	prefs, err := parsePreferences(*prefsFlag)
+	if err != nil {
+		return usageError("%v", err)
+	}
+	if *series != "" && !models.ValidSeriesID(*series) {
+		return usageError("--series may only contain letters, digits, '.', '_' and '-'")
+	}
+
+	feed := models.Feed{
+		Name:        *name,
+		RSSUrl:      *url,
+		Type:        models.FeedType(*feedType),
+		Category:    *category,
+		Source:      source,
+		Torrent:     handoff,
+		Preferences: prefs,
+		SeriesID:    *series,
+	}
+	if *anilist != "" {
+		feed.AnilistUrl = anilist
+	}
+	if *search != "" {
+		feed.SearchText = search
+	}
+
+	b, err := opts.backend()
+	if err != nil {
+		return fail(err)
+	}
+	defer b.Close()
+
+	added, err := b.AddFeed(feed)
+	if err != nil {
+		return fail(err)
+	}
+
+	if opts.json {
+		return printJSON(added)
+	}
+	fmt.Printf("✅ Added %s (%s)\n", added.Name, added.ID)
+	return 0
+}
+
+func runFeedsRemove(args []string) int {
+	var opts cliOptions
+	flags := newFlags("feeds rm", &opts)
+	ids, err := parseFlags(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(ids) == 0 {
+		return usageError("Missing feed ID")
+	}
+
+	b, err := opts.backend()
+	if err != nil {
+		return fail(err)
+	}
+	defer b.Close()
+
+	status := 0
+	for _, id := range ids {
+		if err := b.DeleteFeed(id); err != nil {
+			status = fail(fmt.Errorf("%s: %w", id, err))
+			continue
+		}
+		if !opts.json {
+			fmt.Printf("🗑️ Deleted %s\n", id)
+		}
+	}
+
+	if opts.json {
+		printJSON(map[string]interface{}{"success": status == 0})
+	}
+	return status
+}
+
+func runFeedsEdit(args []string) int {
+	var opts cliOptions
+	flags := newFlags("feeds edit", &opts)
+	flags.String("name", "", "feed name")
+	flags.String("url", "", "RSS URL")
+	flags.String("type", "", "manga or anime")
+	flags.String("category", "", "category")
+	flags.String("anilist", "", "AniList URL")
+	flags.String("search", "", "only track items containing this text (anime)")
+	sourceFlag := flags.String("source", "", "source settings as JSON, empty for RSS")
+	torrentFlag := flags.String("torrent", "", "torrent client hand-off settings as JSON, empty for none")
+	prefsFlag := flags.String("preferences", "", "release preferences as JSON, empty for none")
+	flags.String("series", "", "series ID shared with other feeds of the same series, empty for none")
+	ids, err := parseFlags(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(ids) != 1 {
+		return usageError("Expected exactly one feed ID")
+	}
+
+	// Only the flags that were given are changed
+	fields := map[string]string{
+		"name":     "name",
+		"url":      "rssUrl",
+		"type":     "type",
+		"category": "category",
+		"anilist":  "anilistUrl",
+		"search":   "searchText",
+		"series":   "seriesId",
+	}
+	updates := make(map[string]interface{})
+	var flagErr error
+	flags.Visit(func(f *flag.Flag) {
+		if field, ok := fields[f.Name]; ok {
+			updates[field] = f.Value.String()
+		}
+		var err error
+		switch f.Name {
+		case "source":
+			updates["source"], err = parseSource(*sourceFlag)
+		case "torrent":
+			updates["torrent"], err = parseTorrent(*torrentFlag)
+		case "preferences":
+			updates["preferences"], err = parsePreferences(*prefsFlag)
+		}
+		if err != nil {
+			flagErr = err
+		}
+	})
+	if flagErr != nil {
+		return usageError("%v", flagErr)
+	}
+
+	if len(updates) == 0 {
+		return usageError("Nothing to change")
+	}
+	if feedType, ok := updates["type"].(string); ok && !validFeedType(feedType) {
+		return usageError("--type must be manga or anime")
+	}
+	if seriesID, ok := updates["seriesId"].(string); ok && seriesID != "" && !models.ValidSeriesID(seriesID) {
+		return usageError("--series may only contain letters, digits, '.', '_' and '-'")
+	}
+
+	b, err := opts.backend()
+	if err != nil {
+		return fail(err)
+	}
+	defer b.Close()
+
+	feed, err := b.UpdateFeed(ids[0], updates)
+	if err != nil {
+		return fail(err)
+	}
+
+	if opts.json {
+		return printJSON(feed)
+	}
+	fmt.Printf("✅ Updated %s (%s)\n", feed.Name, feed.ID)
+	return 0
+}
+
+// runCheck implements `shinkan check [--feed id] [--dry-run]`
+func runCheck(args []string) int {
+	var opts cliOptions
+	flags := newFlags("check", &opts)
+	feedID := flags.String("feed", "", "only check this feed")
+	dryRun := flags.Bool("dry-run", false, "show what the check would do without notifying or saving anything")
+	if _, err := parseFlags(flags, args); err != nil {
+		return 2
+	}
+
+	b, err := opts.backend()
+	if err != nil {
+		return fail(err)
+	}
+	defer b.Close()
+
+	ctx, stop := commandContext()
+	defer stop()
+
+	if *dryRun {
+		previews, err := b.DryRun(ctx, *feedID)
+		if err != nil {
+			return fail(err)
+		}
+		if opts.json {
+			return printJSON(previews)
+		}
+		printPreviews(previews, *feedID != "")
+		return 0
+	}
+
+	if *feedID != "" {
+		feed, err := b.CheckFeed(ctx, *feedID)
+		if err != nil {
+			return fail(err)
+		}
+		if opts.json {
+			return printJSON(feed)
+		}
+		fmt.Printf("✅ %s: %s\n", feed.Name, valueOr(feed.LastChapter, "no release yet"))
+		return 0
+	}
+
+	job, err := b.CheckAll(ctx)
+	if err != nil {
+		return fail(err)
+	}
+	if opts.json {
+		return printJSON(job)
+	}
+	fmt.Println(job.Summary())
+	if job.Status != models.JobFinished || job.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// printPreviews prints one line per feed, and the parsed items as well if
+// items is set
+func printPreviews(previews []models.CheckPreview, items bool) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tACTION\tLATEST\tNOTIFY")
+	for _, preview := range previews {
+		latest := preview.Error
+		if preview.Latest != nil {
+			latest = preview.Latest.Title
+		}
+		notify := strings.Join(preview.Notify, ", ")
+		if notify == "" {
+			notify = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", preview.FeedID, preview.FeedName, preview.Action, latest, notify)
+	}
+	w.Flush()
+
+	if !items {
+		return
+	}
+	for _, preview := range previews {
+		fmt.Printf("\n%d item(s):\n", len(preview.Items))
+		for _, item := range preview.Items {
+			marker := "  "
+			if item.New {
+				marker = "🆕"
+			} else if item.Matches {
+				marker = "✓ "
+			}
+			fmt.Printf("  %s %s\n", marker, item.Title)
+		}
+	}
+}
+
+// runTest implements `shinkan test <id>`
+func runTest(args []string) int {
+	var opts cliOptions
+	flags := newFlags("test", &opts)
+	ids, err := parseFlags(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(ids) != 1 {
+		return usageError("Expected exactly one feed ID")
+	}
+
+	b, err := opts.backend()
+	if err != nil {
+		return fail(err)
+	}
+	defer b.Close()
+
+	ctx, stop := commandContext()
+	defer stop()
+
+	result, err := b.TestFeed(ctx, ids[0])
+	if err != nil {
+		return fail(err)
+	}
+
+	if opts.json {
+		return printJSON(result)
+	}
+	if msg, ok := result["error"].(string); ok {
+		fmt.Printf("⚠️ %s\n", msg)
+		return 1
+	}
+	fmt.Printf("🧪 Test notification sent: %v\n   %v\n", result["title"], result["link"])
+	return 0
+}
+
+// readImportFile reads feeds from an OPML file or a JSON export, upgrading
+// exports from older versions. JSON exports also carry their series.
+func readImportFile(path string) ([]models.Feed, []models.Series, error) {
+	if strings.EqualFold(filepath.Ext(path), ".opml") {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer file.Close()
+		feeds, err := opml.Parse(file)
+		return feeds, nil, err
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	raw, _, err = migrations.MigrateImport(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var data struct {
+		Feeds  []models.Feed   `json:"feeds"`
+		Series []models.Series `json:"series"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, nil, fmt.Errorf("invalid import file: %w", err)
+	}
+	if data.Feeds == nil {
+		return nil, nil, errors.New("invalid import file: no feeds")
+	}
+	return data.Feeds, data.Series, nil
+}
+
+// runImport implements `shinkan import <file>`
+func runImport(args []string) int {
+	var opts cliOptions
+	flags := newFlags("import", &opts)
+	strategy := flags.String("strategy", string(storage.StrategySkip), "what to do with feeds that already exist: skip, overwrite, merge or keep_state")
+	preserveState := flags.Bool("preserve-state", false, "keep the check state of imported feeds")
+	dryRun := flags.Bool("dry-run", false, "show the changes without saving them")
+	files, err := parseFlags(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(files) != 1 {
+		return usageError("Expected exactly one file")
+	}
+	if !storage.ImportStrategy(*strategy).Valid() {
+		return usageError("--strategy must be skip, overwrite, merge or keep_state")
+	}
+
+	feeds, series, err := readImportFile(files[0])
+	if err != nil {
+		return fail(err)
+	}
+
+	b, err := opts.backend()
+	if err != nil {
+		return fail(err)
+	}
+	defer b.Close()
+
+	report, err := b.Import(feeds, storage.ImportOptions{
+		Strategy:      storage.ImportStrategy(*strategy),
+		PreserveState: *preserveState,
+		DryRun:        *dryRun,
+		Series:        series,
+	})
+	if err != nil {
+		return fail(err)
+	}
+
+	if opts.json {
+		return printJSON(report)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ACTION\tNAME\tRSS URL\tDETAILS")
+	for _, change := range report.Changes {
+		details := change.Reason
+		if len(change.Fields) > 0 {
+			details = strings.Join(change.Fields, ", ")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", change.Action, change.Feed.Name, change.Feed.RSSUrl, details)
+	}
+	w.Flush()
+
+	verb := "Imported"
+	if report.DryRun {
+		verb = "Dry run"
+	}
+	fmt.Printf("\n%s: %d added, %d skipped, %d overwritten, %d merged, %d kept state\n", verb, report.Added, report.Skipped, report.Overwritten, report.Merged, report.KeptState)
+	return 0
+}
+
+// runExport implements `shinkan export <file|->`
+func runExport(args []string) int {
+	var opts cliOptions
+	flags := newFlags("export", &opts)
+	format := flags.String("format", "", "json or opml, by default from the file extension")
+	files, err := parseFlags(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(files) != 1 {
+		return usageError("Expected exactly one file, or - for standard output")
+	}
+
+	path := files[0]
+	if *format == "" {
+		*format = "json"
+		if strings.EqualFold(filepath.Ext(path), ".opml") {
+			*format = "opml"
+		}
+	}
+	if *format != "json" && *format != "opml" {
+		return usageError("--format must be json or opml")
+	}
+
+	b, err := opts.backend()
+	if err != nil {
+		return fail(err)
+	}
+	defer b.Close()
+
+	feeds, err := b.ListFeeds()
+	if err != nil {
+		return fail(err)
+	}
+	series, err := b.ListSeries()
+	if err != nil {
+		return fail(err)
+	}
+
+	out := os.Stdout
+	if path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			return fail(err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if *format == "opml" {
+		err = opml.Write(out, "Shinkan Rebirth feeds", feeds)
+	} else {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		err = encoder.Encode(map[string]interface{}{
+			"exported": time.Now().Format(time.RFC3339),
+			"version":  models.SchemaVersion,
+			"count":    len(feeds),
+			"feeds":    feeds,
+			"series":   series,
+		})
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	if path != "-" {
+		fmt.Fprintf(os.Stderr, "📦 Exported %d feed(s) to %s\n", len(feeds), path)
+	}
+	return 0
+}
+
+// runStats implements `shinkan stats`
+func runStats(args []string) int {
+	var opts cliOptions
+	flags := newFlags("stats", &opts)
+	if _, err := parseFlags(flags, args); err != nil {
+		return 2
+	}
+
+	b, err := opts.backend()
+	if err != nil {
+		return fail(err)
+	}
+	defer b.Close()
+
+	summary, err := b.Stats()
+	if err != nil {
+		return fail(err)
+	}
+
+	if opts.json {
+		return printJSON(summary)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Feeds\t%d\n", summary.TotalFeeds)
+	fmt.Fprintf(w, "Categories\t%d\n", summary.Categories)
+	fmt.Fprintf(w, "Feeds with errors\t%d\n", summary.FeedsWithErrors)
+	fmt.Fprintf(w, "Never checked\t%d\n", summary.FeedsNeverChecked)
+	fmt.Fprintf(w, "Checks\t%d (%d ok, %d failed)\n", summary.TotalChecks, summary.SuccessfulChecks, summary.FailedChecks)
+	fmt.Fprintf(w, "Notifications sent\t%d\n", summary.NotificationsSent)
+	if summary.LastCheckTime != nil {
+		fmt.Fprintf(w, "Last check\t%s\n", *summary.LastCheckTime)
+	}
+	for kind, count := range summary.ErrorKinds {
+		fmt.Fprintf(w, "Errors (%s)\t%d\n", kind, count)
+	}
+	w.Flush()
+	return 0
+}
+
+// runConfig implements `shinkan config validate`
+func runConfig(args []string) int {
+	if len(args) == 0 || args[0] != "validate" {
+		return usageError("Expected: shinkan config validate")
+	}
+
+	var opts cliOptions
+	flags := newFlags("config validate", &opts)
+	if _, err := parseFlags(flags, args[1:]); err != nil {
+		return 2
+	}
+
+	cfg := config.Read()
+	problems := []string{}
+
+	if err := cfg.Validate(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if _, err := newSchedule(cfg); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if _, err := cron.ParseStandard(cfg.CheckInterval); err != nil {
+		problems = append(problems, fmt.Sprintf("CHECK_INTERVAL %q is not a valid cron expression: %v", cfg.CheckInterval, err))
+	}
+	if authManager, err := auth.New(cfg.AdminPassword, cfg.APIKeysFile, cfg.UsersFile, cfg.SessionTTL); err != nil {
+		problems = append(problems, err.Error())
+	} else if _, err := authManager.ListUsers(); err != nil {
+		problems = append(problems, fmt.Sprintf("%s: %v", cfg.UsersFile, err))
+	}
+	for _, path := range dataFiles(cfg) {
+		if _, err := migrations.MigrateFile(path, true); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if opts.json {
+		printJSON(map[string]interface{}{"valid": len(problems) == 0, "problems": problems})
+	} else if len(problems) == 0 {
+		fmt.Println("✅ Configuration is valid")
+	} else {
+		for _, problem := range problems {
+			fmt.Printf("❌ %s\n", problem)
+		}
+	}
+
+	if len(problems) > 0 {
+		return 1
+	}
+	return 0
+}
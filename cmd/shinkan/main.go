@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -8,10 +9,16 @@ import (
 	"syscall"
 	"time"
 
+	"shinkan-rebirth/internal/auth"
 	"shinkan-rebirth/internal/checker"
 	"shinkan-rebirth/internal/config"
+	"shinkan-rebirth/internal/discovery"
+	"shinkan-rebirth/internal/events"
+	"shinkan-rebirth/internal/importer"
+	"shinkan-rebirth/internal/metrics"
 	"shinkan-rebirth/internal/notifier"
 	"shinkan-rebirth/internal/quotes"
+	"shinkan-rebirth/internal/stats"
 	"shinkan-rebirth/internal/storage"
 	"shinkan-rebirth/internal/web"
 
@@ -31,24 +38,107 @@ const banner = `
 `
 
 func main() {
-	fmt.Println(banner)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			os.Exit(runMigrate(os.Args[2:]))
+		case "help", "-h", "--help":
+			fmt.Print(usage)
+			os.Exit(0)
+		default:
+			if status, ok := runCLI(os.Args[1], os.Args[2:]); ok {
+				os.Exit(status)
+			}
+			fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", os.Args[1], usage)
+			os.Exit(2)
+		}
+	}
+
+	fmt.Print(banner)
 
 	// Load configuration
 	cfg := config.Load()
 
+	// Cancelled on interrupt so in-flight checks stop promptly
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Upgrade data files from older versions before anything reads them
+	if err := migrateDataFiles(cfg); err != nil {
+		log.Fatalf("❌ Failed to migrate data files: %v", err)
+	}
+
 	// Initialize components
 	store := storage.New(cfg.MangaDataFile, cfg.AnimeDataFile)
-	notify := notifier.New(cfg.GotifyServer, cfg.GotifyToken, cfg.DiscordToken, cfg.DiscordChannelID)
-	check := checker.New(store, notify)
+	bus := events.New()
+	authManager, err := auth.New(cfg.AdminPassword, cfg.APIKeysFile, cfg.UsersFile, cfg.SessionTTL)
+	if err != nil {
+		log.Fatalf("❌ Failed to set up authentication: %v", err)
+	}
+	if !authManager.Enabled() {
+		log.Println("⚠️ ADMIN_PASSWORD_HASH not set, web UI and API are open to anyone who can reach them")
+	}
+	schedule, err := newSchedule(cfg)
+	if err != nil {
+		log.Fatalf("❌ ERROR: %v", err)
+	}
+	notify := notifier.New(cfg.GotifyServer, cfg.GotifyToken, cfg.DiscordToken, cfg.DiscordChannelID, cfg.NotifyTimeout, cfg.DedupeWindow, cfg.DedupeFile, schedule, bus, authManager.Targets)
+	go notify.Run(ctx)
+	check := checker.New(store, notify, bus, cfg.FetchTimeout, cfg.MangaDexAPI)
+	collector, err := stats.New(cfg.StatsFile)
+	if err != nil {
+		log.Fatalf("❌ Failed to load stats: %v", err)
+	}
+	go collector.Run(ctx, cfg.StatsInterval)
+	prom := metrics.New()
+	imports := importer.New(cfg.FetchTimeout, cfg.ImportAnimeRSS)
+	discoverer := discovery.New(cfg.FetchTimeout)
+	handoff := newTorrentHandoff(cfg, bus)
+	store.ObserveWrites(prom.ObserveStorageWrite)
+
+	// Subscribers react to check results independently of the checker
+	bus.Handle(collector.HandleEvent)
+	bus.Handle(prom.HandleEvent)
+	bus.Handle(notify.HandleEvent)
+	bus.Handle(handoff.HandleEvent)
+
 	quoteManager, err := quotes.New("./data/quotes.json")
 	if err != nil {
 		log.Printf("⚠️ Failed to load quotes: %v\n", err)
 	}
-	
+
 	// Register Discord slash commands
 	if err := notify.RegisterCommands(
-		func() { check.CheckAll() },
-		func() string { 
+		func(watchCtx context.Context) <-chan string {
+			job, started := check.StartCheckAll(ctx, checker.TriggerDiscord)
+			progress := make(chan string)
+			go func() {
+				defer close(progress)
+				send := func(content string) bool {
+					select {
+					case progress <- content:
+						return true
+					case <-watchCtx.Done():
+						return false
+					}
+				}
+
+				first := "🔍 Starting manual check..."
+				if !started {
+					first = "⏳ A check is already running, following its progress..."
+				}
+				if !send(first) {
+					return
+				}
+				for j := range check.WatchJob(watchCtx, job.ID, 3*time.Second) {
+					if !send(j.Summary()) {
+						return
+					}
+				}
+			}()
+			return progress
+		},
+		func() string {
 			if quoteManager != nil {
 				return quoteManager.GetRandom()
 			}
@@ -57,7 +147,7 @@ func main() {
 	); err != nil {
 		log.Printf("⚠️ Failed to register commands: %v\n", err)
 	}
-	
+
 	// Ensure notifier cleanup on exit
 	defer notify.Close()
 
@@ -65,7 +155,7 @@ func main() {
 	startTime := time.Now()
 
 	// Start web server in goroutine
-	server := web.New(store, check, startTime)
+	server := web.New(ctx, store, check, collector, prom, imports, discoverer, handoff, bus, authManager, cfg.CORSOrigins, startTime)
 	go func() {
 		if err := server.Start(cfg.WebPort); err != nil {
 			log.Fatalf("❌ Failed to start web server: %v", err)
@@ -76,13 +166,13 @@ func main() {
 
 	// Initial check
 	log.Println("🔍 Starting initial feed check...")
-	check.CheckAll()
+	check.CheckAll(ctx, checker.TriggerStartup)
 
 	// Setup cron scheduler
 	c := cron.New()
 	_, cronErr := c.AddFunc(cfg.CheckInterval, func() {
 		log.Println("⏰ Scheduled check triggered")
-		check.CheckAll()
+		check.CheckAll(ctx, checker.TriggerSchedule)
 	})
 
 	if cronErr != nil {
@@ -93,12 +183,28 @@ func main() {
 	log.Printf("⏰ Scheduled checks: %s\n", cfg.CheckInterval)
 
 	// Wait for interrupt signal
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-
-	<-sigChan
+	<-ctx.Done()
+	stop()
 	log.Println("\n👋 Shutting down gracefully...")
-	c.Stop()
+
+	// Stop scheduling new checks and wait for the cancelled ones to return
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	select {
+	case <-c.Stop().Done():
+	case <-shutdownCtx.Done():
+	}
+	if err := server.Shutdown(cfg.ShutdownTimeout); err != nil {
+		log.Printf("⚠️ Web server shutdown: %v\n", err)
+	}
+	if err := check.Wait(shutdownCtx); err != nil {
+		log.Printf("⚠️ Timed out waiting for in-flight checks: %v\n", err)
+	}
+	if err := collector.Save(); err != nil {
+		log.Printf("⚠️ Failed to save stats: %v\n", err)
+	}
+
 	notify.Close()
 	log.Println("✅ Goodbye!")
 }
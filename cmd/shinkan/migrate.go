@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"shinkan-rebirth/internal/config"
+	"shinkan-rebirth/internal/migrations"
+)
+
+// dataFiles are the files covered by schema migrations
+func dataFiles(cfg *config.Config) []string {
+	return []string{cfg.MangaDataFile, cfg.AnimeDataFile}
+}
+
+// migrateDataFiles upgrades the data files before storage opens them
+func migrateDataFiles(cfg *config.Config) error {
+	for _, path := range dataFiles(cfg) {
+		result, err := migrations.MigrateFile(path, false)
+		if err != nil {
+			return err
+		}
+		if len(result.Applied) == 0 {
+			continue
+		}
+
+		log.Printf("🔧 Migrated %s from schema v%d to v%d (backup: %s)\n", path, result.From, result.To, result.Backup)
+		for _, m := range result.Applied {
+			log.Printf("   v%d: %s\n", m.Version, m.Description)
+		}
+	}
+	return nil
+}
+
+// runMigrate implements `shinkan migrate [--dry-run]`
+func runMigrate(args []string) int {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "show the migrations that would run without changing any files")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: shinkan migrate [--dry-run]")
+		fmt.Fprintln(flags.Output(), "\nUpgrades the data files to the current schema, keeping a backup of each.")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	cfg := config.Read()
+	failed := false
+
+	for _, path := range dataFiles(cfg) {
+		result, err := migrations.MigrateFile(path, *dryRun)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			failed = true
+			continue
+		}
+
+		if len(result.Applied) == 0 {
+			fmt.Printf("✓ %s is up to date (schema v%d)\n", path, result.From)
+			continue
+		}
+
+		if *dryRun {
+			fmt.Printf("🔍 %s would be migrated from schema v%d to v%d:\n", path, result.From, result.To)
+		} else {
+			fmt.Printf("✅ %s migrated from schema v%d to v%d:\n", path, result.From, result.To)
+		}
+		for _, m := range result.Applied {
+			fmt.Printf("   v%d: %s\n", m.Version, m.Description)
+		}
+		if result.Backup != "" {
+			fmt.Printf("   📁 Backup: %s\n", result.Backup)
+		}
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
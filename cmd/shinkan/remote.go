@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"shinkan-rebirth/internal/models"
+	"shinkan-rebirth/internal/storage"
+)
+
+// remoteBackend talks to a running instance over its HTTP API. Requests are
+// made as the owner of the API key.
+type remoteBackend struct {
+	server     string
+	apiKey     string
+	httpClient *http.Client
+}
+
+func newRemoteBackend(server, apiKey string) *remoteBackend {
+	return &remoteBackend{
+		server: strings.TrimSuffix(server, "/"),
+		apiKey: apiKey,
+		// Checks can take a while, the server bounds each feed request itself
+		httpClient: &http.Client{Timeout: 10 * time.Minute},
+	}
+}
+
+// do sends a request and decodes the JSON response into out, turning error
+// responses into errors
+func (b *remoteBackend) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, b.server+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if b.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+b.apiKey)
+	}
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("%s (HTTP %d)", apiErr.Error, resp.StatusCode)
+		}
+		return fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (b *remoteBackend) ListFeeds() ([]models.Feed, error) {
+	var feeds []models.Feed
+	err := b.do(context.Background(), "GET", "/api/feeds", nil, &feeds)
+	return feeds, err
+}
+
+func (b *remoteBackend) GetFeed(id string) (*models.Feed, error) {
+	feeds, err := b.ListFeeds()
+	if err != nil {
+		return nil, err
+	}
+	for i := range feeds {
+		if feeds[i].ID == id {
+			return &feeds[i], nil
+		}
+	}
+	return nil, storage.ErrFeedNotFound
+}
+
+func (b *remoteBackend) AddFeed(feed models.Feed) (models.Feed, error) {
+	var added models.Feed
+	err := b.do(context.Background(), "POST", "/api/feeds", feed, &added)
+	return added, err
+}
+
+// UpdateFeed sends the whole feed, as the API replaces it on update
+func (b *remoteBackend) UpdateFeed(id string, updates map[string]interface{}) (*models.Feed, error) {
+	feed, err := b.GetFeed(id)
+	if err != nil {
+		return nil, err
+	}
+
+	body := map[string]interface{}{
+		"name":        feed.Name,
+		"rssUrl":      feed.RSSUrl,
+		"type":        feed.Type,
+		"category":    feed.Category,
+		"anilistUrl":  feed.AnilistUrl,
+		"searchText":  feed.SearchText,
+		"source":      feed.Source,
+		"torrent":     feed.Torrent,
+		"preferences": feed.Preferences,
+		"seriesId":    feed.SeriesID,
+	}
+	for key, value := range updates {
+		body[key] = value
+	}
+
+	var updated models.Feed
+	if err := b.do(context.Background(), "PUT", "/api/feeds/"+id, body, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+func (b *remoteBackend) DeleteFeed(id string) error {
+	return b.do(context.Background(), "DELETE", "/api/feeds/"+id, nil, nil)
+}
+
+func (b *remoteBackend) ListSeries() ([]models.Series, error) {
+	var series []models.Series
+	err := b.do(context.Background(), "GET", "/api/series", nil, &series)
+	return series, err
+}
+
+// UpdateSeries sends the whole series, as the API replaces it on update
+func (b *remoteBackend) UpdateSeries(id string, updates map[string]interface{}) (*models.Series, error) {
+	var series models.Series
+	if err := b.do(context.Background(), "GET", "/api/series/"+id, nil, &series); err != nil {
+		return nil, err
+	}
+
+	body := map[string]interface{}{
+		"title":     series.Title,
+		"aliases":   series.Aliases,
+		"anilistId": series.AnilistID,
+		"cover":     series.Cover,
+		"status":    series.Status,
+		"category":  series.Category,
+		"progress":  series.Progress,
+	}
+	for key, value := range updates {
+		body[key] = value
+	}
+
+	var updated models.Series
+	if err := b.do(context.Background(), "PUT", "/api/series/"+id, body, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+func (b *remoteBackend) CheckFeed(ctx context.Context, id string) (*models.Feed, error) {
+	if err := b.do(ctx, "POST", "/api/feeds/"+id+"/check", nil, nil); err != nil {
+		return nil, err
+	}
+	return b.GetFeed(id)
+}
+
+// CheckAll starts a check, or joins the running one, and polls until it
+// finishes
+func (b *remoteBackend) CheckAll(ctx context.Context) (models.CheckJob, error) {
+	var started struct {
+		Job models.CheckJob `json:"job"`
+	}
+	if err := b.do(ctx, "POST", "/api/check", nil, &started); err != nil {
+		return models.CheckJob{}, err
+	}
+
+	for {
+		var status struct {
+			Running bool             `json:"running"`
+			Job     *models.CheckJob `json:"job"`
+		}
+		if err := b.do(ctx, "GET", "/api/check/status", nil, &status); err != nil {
+			return models.CheckJob{}, err
+		}
+		if status.Job == nil {
+			return started.Job, nil
+		}
+		if !status.Running {
+			return *status.Job, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return *status.Job, ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+func (b *remoteBackend) DryRun(ctx context.Context, id string) ([]models.CheckPreview, error) {
+	if id == "" {
+		var result struct {
+			Previews []models.CheckPreview `json:"previews"`
+		}
+		err := b.do(ctx, "POST", "/api/check?dryRun=true", nil, &result)
+		return result.Previews, err
+	}
+
+	var preview models.CheckPreview
+	if err := b.do(ctx, "POST", "/api/feeds/"+id+"/check?dryRun=true", nil, &preview); err != nil {
+		return nil, err
+	}
+	return []models.CheckPreview{preview}, nil
+}
+
+func (b *remoteBackend) TestFeed(ctx context.Context, id string) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	err := b.do(ctx, "POST", "/api/feeds/"+id+"/test", nil, &result)
+	return result, err
+}
+
+func (b *remoteBackend) Import(feeds []models.Feed, opts storage.ImportOptions) (storage.ImportReport, error) {
+	body := map[string]interface{}{
+		"feeds":         feeds,
+		"series":        opts.Series,
+		"strategy":      opts.Strategy,
+		"preserveState": opts.PreserveState,
+		"dryRun":        opts.DryRun,
+	}
+
+	var result struct {
+		Report storage.ImportReport `json:"report"`
+	}
+	err := b.do(context.Background(), "POST", "/api/import", body, &result)
+	return result.Report, err
+}
+
+func (b *remoteBackend) Stats() (models.Stats, error) {
+	var summary models.Stats
+	err := b.do(context.Background(), "GET", "/api/stats", nil, &summary)
+	return summary, err
+}
+
+func (b *remoteBackend) Close() {}
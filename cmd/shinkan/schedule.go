@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"shinkan-rebirth/internal/config"
+	"shinkan-rebirth/internal/notifier"
+)
+
+// newSchedule reads the quiet hours and batch window of the notifications.
+// Channels without quiet hours of their own use the global ones.
+func newSchedule(cfg *config.Config) (notifier.Schedule, error) {
+	schedule := notifier.Schedule{
+		Quiet:       make(map[string]*notifier.QuietHours),
+		BatchWindow: cfg.NotifyBatchWindow,
+		QueueFile:   cfg.NotifyQueueFile,
+	}
+
+	loc := time.Local
+	if cfg.QuietHoursTimezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(cfg.QuietHoursTimezone); err != nil {
+			return schedule, fmt.Errorf("QUIET_HOURS_TZ %q is not a known time zone", cfg.QuietHoursTimezone)
+		}
+	}
+
+	specs := map[string]struct{ key, spec string }{
+		notifier.ChannelGotify:  {"QUIET_HOURS_GOTIFY", cfg.QuietHoursGotify},
+		notifier.ChannelDiscord: {"QUIET_HOURS_DISCORD", cfg.QuietHoursDiscord},
+	}
+	for channel, setting := range specs {
+		if setting.spec == "" {
+			setting.key, setting.spec = "QUIET_HOURS", cfg.QuietHours
+		}
+		quiet, err := notifier.ParseQuietHours(setting.spec, loc)
+		if err != nil {
+			return schedule, fmt.Errorf("%s: %w", setting.key, err)
+		}
+		schedule.Quiet[channel] = quiet
+	}
+	return schedule, nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"shinkan-rebirth/internal/models"
+)
+
+func runSeries(args []string) int {
+	if len(args) == 0 {
+		return usageError("Missing series command")
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		return runSeriesList(args[1:])
+	case "edit":
+		return runSeriesEdit(args[1:])
+	default:
+		return usageError("Unknown series command %q", args[0])
+	}
+}
+
+func printSeries(series []models.Series) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTYPE\tTITLE\tCATEGORY\tSTATUS\tPROGRESS")
+	for _, s := range series {
+		progress := s.Progress
+		if progress == "" {
+			progress = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", s.ID, s.Type, s.Title, s.Category, s.Status, progress)
+	}
+	w.Flush()
+}
+
+func runSeriesList(args []string) int {
+	var opts cliOptions
+	flags := newFlags("series list", &opts)
+	status := flags.String("status", "", "only list series with this status")
+	category := flags.String("category", "", "only list series in this category")
+	if _, err := parseFlags(flags, args); err != nil {
+		return 2
+	}
+
+	b, err := opts.backend()
+	if err != nil {
+		return fail(err)
+	}
+	defer b.Close()
+
+	series, err := b.ListSeries()
+	if err != nil {
+		return fail(err)
+	}
+
+	filtered := make([]models.Series, 0, len(series))
+	for _, s := range series {
+		if *status != "" && string(s.Status) != *status {
+			continue
+		}
+		if *category != "" && !strings.EqualFold(s.Category, *category) {
+			continue
+		}
+		filtered = append(filtered, s)
+	}
+
+	if opts.json {
+		return printJSON(filtered)
+	}
+	printSeries(filtered)
+	return 0
+}
+
+// runSeriesEdit implements `shinkan series edit <id> [flags]`
+func runSeriesEdit(args []string) int {
+	var opts cliOptions
+	flags := newFlags("series edit", &opts)
+	title := flags.String("title", "", "series title")
+	aliases := flags.String("aliases", "", "comma-separated other titles, empty for none")
+	anilist := flags.String("anilist", "", "AniList URL or ID, empty for none")
+	cover := flags.String("cover", "", "cover image URL")
+	status := flags.String("status", "", "current, planning, paused, completed or dropped")
+	category := flags.String("category", "", "category, also applied to the series' feeds")
+	progress := flags.String("progress", "", "last chapter or episode read")
+	ids, err := parseFlags(flags, args)
+	if err != nil {
+		return 2
+	}
+	if len(ids) != 1 {
+		return usageError("Expected exactly one series ID")
+	}
+
+	// Only the flags that were given are changed
+	updates := make(map[string]interface{})
+	var flagErr error
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "title":
+			if strings.TrimSpace(*title) == "" {
+				flagErr = fmt.Errorf("--title can't be empty")
+			}
+			updates["title"] = strings.TrimSpace(*title)
+		case "aliases":
+			list := make([]string, 0)
+			for _, alias := range strings.Split(*aliases, ",") {
+				if alias = strings.TrimSpace(alias); alias != "" {
+					list = append(list, alias)
+				}
+			}
+			updates["aliases"] = list
+		case "anilist":
+			id, err := parseAnilist(*anilist)
+			if err != nil {
+				flagErr = err
+			}
+			updates["anilistId"] = id
+		case "cover":
+			updates["cover"] = *cover
+		case "status":
+			if !models.SeriesStatus(*status).Valid() {
+				flagErr = fmt.Errorf("--status must be current, planning, paused, completed or dropped")
+			}
+			updates["status"] = models.SeriesStatus(*status)
+		case "category":
+			updates["category"] = *category
+		case "progress":
+			updates["progress"] = strings.TrimSpace(*progress)
+		}
+	})
+	if flagErr != nil {
+		return usageError("%v", flagErr)
+	}
+	if len(updates) == 0 {
+		return usageError("Nothing to change")
+	}
+
+	b, err := opts.backend()
+	if err != nil {
+		return fail(err)
+	}
+	defer b.Close()
+
+	series, err := b.UpdateSeries(ids[0], updates)
+	if err != nil {
+		return fail(err)
+	}
+
+	if opts.json {
+		return printJSON(series)
+	}
+	fmt.Printf("✅ Updated %s (%s)\n", series.Title, series.ID)
+	return 0
+}
+
+// parseAnilist reads the --anilist flag, an AniList URL or a bare ID.
+// Empty removes the link.
+func parseAnilist(raw string) (int, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, nil
+	}
+	if id, err := strconv.Atoi(raw); err == nil && id > 0 {
+		return id, nil
+	}
+	if id := models.ParseAnilistID(raw); id != 0 {
+		return id, nil
+	}
+	return 0, fmt.Errorf("invalid --anilist %q, use an AniList URL or ID", raw)
+}
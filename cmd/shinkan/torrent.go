@@ -0,0 +1,21 @@
+package main
+
+import (
+	"shinkan-rebirth/internal/config"
+	"shinkan-rebirth/internal/events"
+	"shinkan-rebirth/internal/models"
+	"shinkan-rebirth/internal/torrent"
+)
+
+// newTorrentHandoff sets up hand-off to the torrent clients that are
+// configured
+func newTorrentHandoff(cfg *config.Config, publisher events.Publisher) *torrent.Handoff {
+	clients := make(map[models.TorrentClient]torrent.Client)
+	if cfg.QBittorrentURL != "" {
+		clients[models.TorrentClientQBittorrent] = torrent.NewQBittorrent(cfg.QBittorrentURL, cfg.QBittorrentUsername, cfg.QBittorrentPassword, cfg.NotifyTimeout)
+	}
+	if cfg.TransmissionURL != "" {
+		clients[models.TorrentClientTransmission] = torrent.NewTransmission(cfg.TransmissionURL, cfg.TransmissionUsername, cfg.TransmissionPassword, cfg.NotifyTimeout)
+	}
+	return torrent.New(clients, publisher)
+}
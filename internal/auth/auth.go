@@ -0,0 +1,346 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"shinkan-rebirth/internal/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Prefix of generated API keys, so they're recognisable in configs and logs
+const keyPrefix = "shk_"
+
+var (
+	ErrInvalidPassword = errors.New("invalid password")
+	ErrInvalidScope    = errors.New("scope must be \"read\" or \"admin\"")
+	ErrKeyNotFound     = errors.New("API key not found")
+
+	// errKeyUnchanged tells updateKeys there is nothing to save
+	errKeyUnchanged = errors.New("key unchanged")
+)
+
+// Session is a logged in browser session
+type Session struct {
+	Token     string
+	CSRFToken string
+	UserID    string
+	IsAdmin   bool
+	ExpiresAt time.Time
+}
+
+type keyData struct {
+	Keys []models.APIKey `json:"keys"`
+}
+
+// Manager handles user accounts, logins and API keys. Authentication is
+// only enforced when an admin password hash is configured.
+type Manager struct {
+	passwordHash  []byte
+	keysFilePath  string
+	usersFilePath string
+	sessionTTL    time.Duration
+	sessions      map[string]*Session
+	mu            sync.Mutex // Guards sessions and the users file
+
+	// API keys are loaded once and checked on every request, only changes
+	// go to the keys file
+	keys        []models.APIKey
+	keysMu      sync.RWMutex
+	keysWriteMu sync.Mutex // Serializes changes, so the file and keys agree
+}
+
+func New(passwordHash, keysFilePath, usersFilePath string, sessionTTL time.Duration) (*Manager, error) {
+	m := &Manager{
+		passwordHash:  []byte(passwordHash),
+		keysFilePath:  keysFilePath,
+		usersFilePath: usersFilePath,
+		sessionTTL:    sessionTTL,
+		sessions:      make(map[string]*Session),
+	}
+
+	if passwordHash != "" {
+		if _, err := bcrypt.Cost(m.passwordHash); err != nil {
+			return nil, fmt.Errorf("ADMIN_PASSWORD_HASH is not a bcrypt hash: %w", err)
+		}
+	}
+
+	for _, path := range []string{keysFilePath, usersFilePath} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create data directory: %w", err)
+		}
+	}
+
+	data, err := m.readKeys()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", keysFilePath, err)
+	}
+	m.keys = data.Keys
+
+	return m, nil
+}
+
+// Enabled reports whether requests need to be authenticated
+func (m *Manager) Enabled() bool {
+	return len(m.passwordHash) > 0
+}
+
+// Login checks a user's password and starts a new session. An empty
+// username logs in as the built-in admin.
+func (m *Manager) Login(username, password string) (*Session, error) {
+	m.mu.Lock()
+	user, hash, found, err := m.loginUser(username)
+	m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	// bcrypt is slow on purpose, so compare without holding the lock
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil || !found {
+		return nil, ErrInvalidPassword
+	}
+
+	session := &Session{
+		Token:     randomToken(32),
+		CSRFToken: randomToken(32),
+		UserID:    user.ID,
+		IsAdmin:   user.IsAdmin,
+		ExpiresAt: time.Now().Add(m.sessionTTL),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// The account may have been deleted during the comparison
+	if _, err := m.getUser(user.ID); err != nil {
+		return nil, ErrInvalidPassword
+	}
+
+	m.pruneSessions()
+	m.sessions[session.Token] = session
+
+	return session, nil
+}
+
+func (m *Manager) Logout(token string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, token)
+}
+
+// Session returns the session for a cookie token if it's still valid
+func (m *Manager) Session(token string) (*Session, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[token]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(session.ExpiresAt) {
+		delete(m.sessions, token)
+		return nil, false
+	}
+	return session, true
+}
+
+// ValidCSRF compares a submitted CSRF token against the session's
+func (s *Session) ValidCSRF(token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.CSRFToken)) == 1
+}
+
+// pruneSessions drops expired sessions, must be called with mu held
+func (m *Manager) pruneSessions() {
+	now := time.Now()
+	for token, session := range m.sessions {
+		if now.After(session.ExpiresAt) {
+			delete(m.sessions, token)
+		}
+	}
+}
+
+// CreateKey generates a new API key for a user. The plain key is only
+// returned here, storage keeps a hash of it.
+func (m *Manager) CreateKey(userID, name string, scope models.Scope) (models.APIKey, string, error) {
+	if scope != models.ScopeRead && scope != models.ScopeAdmin {
+		return models.APIKey{}, "", ErrInvalidScope
+	}
+
+	plain := keyPrefix + randomToken(24)
+	key := models.APIKey{
+		ID:        fmt.Sprintf("%d", time.Now().UnixNano()),
+		UserID:    userID,
+		Name:      name,
+		Scope:     scope,
+		Prefix:    plain[:len(keyPrefix)+6],
+		Hash:      hashKey(plain),
+		CreatedAt: time.Now().Format(time.RFC3339),
+	}
+
+	err := m.updateKeys(func(keys []models.APIKey) ([]models.APIKey, error) {
+		return append(keys, key), nil
+	})
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+
+	return key, plain, nil
+}
+
+// ListKeys returns the API keys belonging to a user
+func (m *Manager) ListKeys(userID string) ([]models.APIKey, error) {
+	m.keysMu.RLock()
+	defer m.keysMu.RUnlock()
+
+	keys := []models.APIKey{}
+	for _, key := range m.keys {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (m *Manager) DeleteKey(userID, id string) error {
+	return m.updateKeys(func(keys []models.APIKey) ([]models.APIKey, error) {
+		remaining := make([]models.APIKey, 0, len(keys))
+		for _, key := range keys {
+			if key.ID != id || key.UserID != userID {
+				remaining = append(remaining, key)
+			}
+		}
+
+		if len(remaining) == len(keys) {
+			return nil, ErrKeyNotFound
+		}
+		return remaining, nil
+	})
+}
+
+// Authenticate looks up the API key for a bearer token
+func (m *Manager) Authenticate(plain string) (models.APIKey, bool) {
+	hash := hashKey(plain)
+
+	m.keysMu.RLock()
+	defer m.keysMu.RUnlock()
+
+	for _, key := range m.keys {
+		if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hash)) == 1 {
+			if !recentlyUsed(key, time.Now()) {
+				go m.touchKey(key.ID)
+			}
+			return key, true
+		}
+	}
+
+	return models.APIKey{}, false
+}
+
+// recentlyUsed reports whether a key's last use was recorded less than a
+// minute before now
+func recentlyUsed(key models.APIKey, now time.Time) bool {
+	if key.LastUsedAt == nil {
+		return false
+	}
+	last, err := time.Parse(time.RFC3339, *key.LastUsedAt)
+	return err == nil && now.Sub(last) < time.Minute
+}
+
+// touchKey records when a key was last used, at most once a minute so
+// scripts polling the API don't rewrite the file on every request
+func (m *Manager) touchKey(id string) {
+	now := time.Now()
+	err := m.updateKeys(func(keys []models.APIKey) ([]models.APIKey, error) {
+		for i := range keys {
+			if keys[i].ID == id && !recentlyUsed(keys[i], now) {
+				lastUsed := now.Format(time.RFC3339)
+				keys[i].LastUsedAt = &lastUsed
+				return keys, nil
+			}
+		}
+		return nil, errKeyUnchanged
+	})
+	if err != nil && !errors.Is(err, errKeyUnchanged) {
+		log.Printf("⚠️ Failed to record API key use: %v\n", err)
+	}
+}
+
+// updateKeys applies change to a copy of the keys and saves the result. The
+// new keys are only used once they are saved.
+func (m *Manager) updateKeys(change func([]models.APIKey) ([]models.APIKey, error)) error {
+	m.keysWriteMu.Lock()
+	defer m.keysWriteMu.Unlock()
+
+	m.keysMu.RLock()
+	keys := append([]models.APIKey(nil), m.keys...)
+	m.keysMu.RUnlock()
+
+	keys, err := change(keys)
+	if err != nil {
+		return err
+	}
+	if err := m.writeKeys(keyData{Keys: keys}); err != nil {
+		return err
+	}
+
+	m.keysMu.Lock()
+	m.keys = keys
+	m.keysMu.Unlock()
+	return nil
+}
+
+func (m *Manager) readKeys() (keyData, error) {
+	raw, err := os.ReadFile(m.keysFilePath)
+	if os.IsNotExist(err) {
+		return keyData{Keys: []models.APIKey{}}, nil
+	}
+	if err != nil {
+		return keyData{}, err
+	}
+
+	var data keyData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return keyData{}, err
+	}
+
+	// Keys created before user accounts belong to the admin
+	for i := range data.Keys {
+		if data.Keys[i].UserID == "" {
+			data.Keys[i].UserID = models.DefaultUserID
+		}
+	}
+	return data, nil
+}
+
+func (m *Manager) writeKeys(data keyData) error {
+	raw, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Only hashes are stored, but keep the file private anyway
+	return os.WriteFile(m.keysFilePath, raw, 0600)
+}
+
+func hashKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomToken(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("Failed to generate random token: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,308 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"shinkan-rebirth/internal/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrUserExists   = errors.New("username already taken")
+	ErrInvalidUser  = errors.New("username and password (8+ characters) required")
+	ErrBuiltinUser  = errors.New("the built-in admin account can't be deleted")
+	ErrWeakPassword = errors.New("password must be at least 8 characters")
+	ErrGotifyServer = errors.New("only admin accounts can set a Gotify server")
+)
+
+const minPasswordLength = 8
+
+type userData struct {
+	Users []models.User `json:"users"`
+}
+
+// builtinAdmin is the account backed by ADMIN_PASSWORD_HASH. Its password
+// lives in the config, but its notification targets are stored like any
+// other user's.
+func (m *Manager) builtinAdmin(data userData) models.User {
+	admin := models.User{
+		ID:       models.DefaultUserID,
+		Username: "admin",
+		IsAdmin:  true,
+	}
+	for _, user := range data.Users {
+		if user.ID == models.DefaultUserID {
+			admin.Targets = user.Targets
+			admin.CreatedAt = user.CreatedAt
+		}
+	}
+	return admin
+}
+
+// loginUser returns the user for a username and the hash their password is
+// checked against, must be called with mu held. Unknown usernames get the
+// admin hash and found is false, so probing them takes as long as a real
+// comparison.
+func (m *Manager) loginUser(username string) (user models.User, hash []byte, found bool, err error) {
+	data, err := m.readUsers()
+	if err != nil {
+		return models.User{}, nil, false, err
+	}
+
+	if strings.EqualFold(username, "admin") || username == "" {
+		return m.builtinAdmin(data), m.passwordHash, true, nil
+	}
+
+	for _, user := range data.Users {
+		if user.ID != models.DefaultUserID && strings.EqualFold(user.Username, username) {
+			return user, []byte(user.PasswordHash), true, nil
+		}
+	}
+	return models.User{}, m.passwordHash, false, nil
+}
+
+// GetUser returns a user by ID
+func (m *Manager) GetUser(id string) (models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.getUser(id)
+}
+
+func (m *Manager) getUser(id string) (models.User, error) {
+	data, err := m.readUsers()
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if id == models.DefaultUserID {
+		return m.builtinAdmin(data), nil
+	}
+
+	for _, user := range data.Users {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return models.User{}, ErrUserNotFound
+}
+
+// ListUsers returns every account, including the built-in admin
+func (m *Manager) ListUsers() ([]models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, err := m.readUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	users := []models.User{m.builtinAdmin(data)}
+	for _, user := range data.Users {
+		if user.ID != models.DefaultUserID {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (m *Manager) CreateUser(username, password string, isAdmin bool) (models.User, error) {
+	username = strings.TrimSpace(username)
+	if username == "" || len(password) < minPasswordLength {
+		return models.User{}, ErrInvalidUser
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, err := m.readUsers()
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if strings.EqualFold(username, "admin") {
+		return models.User{}, ErrUserExists
+	}
+	for _, user := range data.Users {
+		if strings.EqualFold(user.Username, username) {
+			return models.User{}, ErrUserExists
+		}
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user := models.User{
+		ID:           fmt.Sprintf("%d", time.Now().UnixNano()),
+		Username:     username,
+		PasswordHash: string(hash),
+		IsAdmin:      isAdmin,
+		CreatedAt:    time.Now().Format(time.RFC3339),
+	}
+
+	data.Users = append(data.Users, user)
+	if err := m.writeUsers(data); err != nil {
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+// UpdateUser changes a user's password and/or notification targets. nil
+// arguments are left unchanged.
+func (m *Manager) UpdateUser(id string, password *string, targets *models.NotificationTargets) (models.User, error) {
+	if password != nil && id == models.DefaultUserID {
+		return models.User{}, fmt.Errorf("the admin password is set with ADMIN_PASSWORD_HASH")
+	}
+	if password != nil && len(*password) < minPasswordLength {
+		return models.User{}, ErrWeakPassword
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, err := m.readUsers()
+	if err != nil {
+		return models.User{}, err
+	}
+
+	index := -1
+	for i, user := range data.Users {
+		if user.ID == id {
+			index = i
+		}
+	}
+
+	if index == -1 {
+		if id != models.DefaultUserID {
+			return models.User{}, ErrUserNotFound
+		}
+		// First settings saved for the built-in admin
+		data.Users = append(data.Users, models.User{
+			ID:        models.DefaultUserID,
+			Username:  "admin",
+			IsAdmin:   true,
+			CreatedAt: time.Now().Format(time.RFC3339),
+		})
+		index = len(data.Users) - 1
+	}
+
+	if password != nil {
+		hash, err := bcrypt.GenerateFromPassword([]byte(*password), bcrypt.DefaultCost)
+		if err != nil {
+			return models.User{}, err
+		}
+		data.Users[index].PasswordHash = string(hash)
+	}
+	if targets != nil {
+		if err := checkTargets(data.Users[index], *targets); err != nil {
+			return models.User{}, err
+		}
+		data.Users[index].Targets = *targets
+	}
+
+	if err := m.writeUsers(data); err != nil {
+		return models.User{}, err
+	}
+
+	return m.getUser(id)
+}
+
+// DeleteUser removes an account and ends its sessions. The caller is
+// responsible for the user's feeds.
+func (m *Manager) DeleteUser(id string) error {
+	if id == models.DefaultUserID {
+		return ErrBuiltinUser
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, err := m.readUsers()
+	if err != nil {
+		return err
+	}
+
+	users := make([]models.User, 0, len(data.Users))
+	for _, user := range data.Users {
+		if user.ID != id {
+			users = append(users, user)
+		}
+	}
+
+	if len(users) == len(data.Users) {
+		return ErrUserNotFound
+	}
+
+	for token, session := range m.sessions {
+		if session.UserID == id {
+			delete(m.sessions, token)
+		}
+	}
+
+	data.Users = users
+	return m.writeUsers(data)
+}
+
+// checkTargets rejects Gotify servers set by non-admins. The server posts
+// to that URL, so anyone else could make it reach internal addresses; they
+// use a token of an application on the global server instead.
+func checkTargets(user models.User, targets models.NotificationTargets) error {
+	if targets.GotifyServer == "" {
+		return nil
+	}
+	if !user.IsAdmin {
+		return ErrGotifyServer
+	}
+	u, err := url.Parse(targets.GotifyServer)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid Gotify server %q, an http(s) URL is required", targets.GotifyServer)
+	}
+	return nil
+}
+
+// Targets returns a user's notification targets, for the notifier. Gotify
+// servers saved by non-admins before they were restricted are ignored.
+func (m *Manager) Targets(userID string) models.NotificationTargets {
+	user, err := m.GetUser(userID)
+	if err != nil {
+		return models.NotificationTargets{}
+	}
+	if !user.IsAdmin {
+		user.Targets.GotifyServer = ""
+	}
+	return user.Targets
+}
+
+func (m *Manager) readUsers() (userData, error) {
+	raw, err := os.ReadFile(m.usersFilePath)
+	if os.IsNotExist(err) {
+		return userData{Users: []models.User{}}, nil
+	}
+	if err != nil {
+		return userData{}, err
+	}
+
+	var data userData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return userData{}, err
+	}
+	return data, nil
+}
+
+func (m *Manager) writeUsers(data userData) error {
+	raw, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(m.usersFilePath, raw, 0600)
+}
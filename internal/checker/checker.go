@@ -1,12 +1,16 @@
 package checker
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
 
+	"shinkan-rebirth/internal/events"
 	"shinkan-rebirth/internal/models"
 	"shinkan-rebirth/internal/notifier"
 	"shinkan-rebirth/internal/storage"
@@ -15,118 +19,293 @@ import (
 )
 
 type Checker struct {
-	storage  *storage.Storage
-	notifier *notifier.Notifier
-	parser   *gofeed.Parser
-	stats    Stats
-	mu       sync.RWMutex
+	storage     *storage.Storage
+	notifier    *notifier.Notifier
+	events      events.Publisher
+	parser      *gofeed.Parser
+	httpClient  *http.Client
+	timeout     time.Duration
+	mangadexAPI string // Base URL of the MangaDex API
+	inFlight    sync.WaitGroup
+	jobMu       sync.Mutex
+	job         *checkJob // Running or last finished check run
 }
 
-type Stats struct {
-	TotalChecks       int
-	SuccessfulChecks  int
-	FailedChecks      int
-	NotificationsSent int
-	LastCheckTime     *string
+// New creates a checker. Check results are reported as events on publisher;
+// notifier is only used directly for test notifications. timeout bounds each
+// individual feed request. mangadexAPI is the API used by MangaDex sources.
+func New(storage *storage.Storage, notifier *notifier.Notifier, publisher events.Publisher, timeout time.Duration, mangadexAPI string) *Checker {
+	return &Checker{
+		storage:     storage,
+		notifier:    notifier,
+		events:      publisher,
+		parser:      gofeed.NewParser(),
+		httpClient:  &http.Client{},
+		timeout:     timeout,
+		mangadexAPI: mangadexAPI,
+	}
 }
 
-func New(storage *storage.Storage, notifier *notifier.Notifier) *Checker {
-	return &Checker{
-		storage:  storage,
-		notifier: notifier,
-		parser:   gofeed.NewParser(),
-		stats:    Stats{},
+// Wait blocks until all in-flight checks have returned or ctx is done
+func (c *Checker) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		c.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
-func (c *Checker) GetStats() Stats {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	return c.stats
+// sleep pauses for d, returning early with ctx's error if it is cancelled
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
-func (c *Checker) ResetStats() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.stats = Stats{}
+// CheckFeed checks a single feed, retrying transient failures
+func (c *Checker) CheckFeed(ctx context.Context, feed models.Feed, retries int) error {
+	return c.checkSource(ctx, []models.Feed{feed}, retries)[0]
 }
 
-func (c *Checker) CheckFeed(feed models.Feed, retries int) error {
-	c.mu.Lock()
-	c.stats.TotalChecks++
-	c.mu.Unlock()
+// checkSource fetches an RSS URL once and checks every feed subscribed to
+// it, so users following the same series don't multiply requests. It
+// returns one error per feed.
+func (c *Checker) checkSource(ctx context.Context, feeds []models.Feed, retries int) []error {
+	c.inFlight.Add(1)
+	defer c.inFlight.Done()
+
+	errs := make([]error, len(feeds))
+
+	rssFeed, err := c.fetchWithRetries(ctx, feeds, retries)
+	if err != nil {
+		// Cancelled from outside (shutdown), don't blame the feeds
+		if ctx.Err() != nil {
+			for i := range errs {
+				errs[i] = ctx.Err()
+			}
+			return errs
+		}
 
-	var lastErr error
+		feedErr := classifyError(err)
+		for i, feed := range feeds {
+			c.recordFailure(ctx, feed, feedErr)
+			errs[i] = feedErr
+		}
+		return errs
+	}
+
+	for i, feed := range feeds {
+		errs[i] = c.processFeed(ctx, feed, rssFeed)
+	}
+	return errs
+}
+
+func (c *Checker) fetchWithRetries(ctx context.Context, feeds []models.Feed, retries int) (*gofeed.Feed, error) {
+	label := feeds[0].Name
+	if len(feeds) > 1 {
+		label = fmt.Sprintf("%s +%d", label, len(feeds)-1)
+	}
+
+	var lastErr *FeedError
 	for attempt := 1; attempt <= retries; attempt++ {
-		err := c.checkFeedOnce(feed)
+		rssFeed, err := c.fetchFeed(ctx, feeds[0])
 		if err == nil {
-			c.mu.Lock()
-			c.stats.SuccessfulChecks++
-			c.mu.Unlock()
-			return nil
+			return rssFeed, nil
 		}
 
-		lastErr = err
-		log.Printf("✗ [%s] Error (attempt %d/%d): %v\n", feed.Name, attempt, retries, err)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		lastErr = classifyError(err)
+		log.Printf("✗ [%s] Error [%s] (attempt %d/%d): %v\n", label, lastErr.Kind, attempt, retries, err)
+
+		if !lastErr.Retryable() {
+			break
+		}
 
 		if attempt < retries {
-			time.Sleep(time.Duration(attempt) * time.Second)
+			if err := sleep(ctx, time.Duration(attempt)*time.Second); err != nil {
+				return nil, err
+			}
 		}
 	}
 
-	c.mu.Lock()
-	c.stats.FailedChecks++
-	c.mu.Unlock()
+	return nil, lastErr
+}
 
-	// Update feed with error
+// recordFailure stores a failed check on the feed and reports it
+func (c *Checker) recordFailure(ctx context.Context, feed models.Feed, feedErr *FeedError) {
 	failCount := feed.FailCount + 1
 	lastChecked := time.Now().Format(time.RFC3339)
-	errorMsg := lastErr.Error()
-	c.storage.UpdateFeed(feed.ID, map[string]interface{}{
+	errorMsg := feedErr.Error()
+	updated, err := c.storage.UpdateFeed(feed.ID, map[string]interface{}{
 		"lastChecked": lastChecked,
 		"lastError":   errorMsg,
+		"errorKind":   feedErr.Kind,
+		"errorStatus": feedErr.StatusCode,
 		"failCount":   failCount,
 	})
+	if err != nil {
+		log.Printf("⚠️ [%s] Failed to save error: %v\n", feed.Name, err)
+		updated = &feed
+	}
+
+	c.events.Publish(ctx, events.FeedFailed{
+		Feed:       *updated,
+		Error:      errorMsg,
+		Kind:       feedErr.Kind,
+		StatusCode: feedErr.StatusCode,
+	})
+}
+
+// publishChecked reports a successful check, and a recovery if the feed had
+// been failing before
+func (c *Checker) publishChecked(ctx context.Context, before, after models.Feed) {
+	if before.FailCount > 0 {
+		previousError := ""
+		if before.LastError != nil {
+			previousError = *before.LastError
+		}
+		log.Printf("💚 [%s] Recovered after %d failed check(s)\n", after.Name, before.FailCount)
+		c.events.Publish(ctx, events.FeedRecovered{
+			Feed:          after,
+			PreviousError: previousError,
+			FailCount:     before.FailCount,
+		})
+	}
+
+	c.events.Publish(ctx, events.FeedChecked{Feed: after})
+}
+
+// fetchFeed reads a feed from its source, reporting how long it took
+func (c *Checker) fetchFeed(ctx context.Context, feed models.Feed) (*gofeed.Feed, error) {
+	start := time.Now()
+	rssFeed, err := c.fetch(ctx, feed)
 
-	return lastErr
+	event := events.FeedFetched{URL: feed.RSSUrl, Duration: time.Since(start)}
+	if err != nil {
+		event.Kind = classifyError(err).Kind
+	}
+	c.events.Publish(ctx, event)
+
+	return rssFeed, err
 }
 
-func (c *Checker) checkFeedOnce(feed models.Feed) error {
-	rssFeed, err := c.parser.ParseURL(feed.RSSUrl)
+func (c *Checker) fetch(ctx context.Context, feed models.Feed) (*gofeed.Feed, error) {
+	source, err := c.sourceFor(feed)
 	if err != nil {
-		return fmt.Errorf("failed to parse RSS: %w", err)
+		return nil, err
+	}
+
+	rssFeed, err := source.Fetch(ctx, feed.RSSUrl)
+	if err != nil {
+		return nil, err
 	}
 
 	if len(rssFeed.Items) == 0 {
-		return fmt.Errorf("no items found in RSS feed")
-	}
-
-	var latestItem *gofeed.Item
-	
-	// For anime feeds with search text, find matching item
-	if feed.Type == models.FeedTypeAnime && feed.SearchText != nil && *feed.SearchText != "" {
-		searchText := strings.ToLower(*feed.SearchText)
-		for _, item := range rssFeed.Items {
-			if strings.Contains(strings.ToLower(item.Title), searchText) {
-				latestItem = item
-				break
-			}
+		return nil, newFeedError(models.ErrorKindEmpty, "no items found in feed")
+	}
+
+	return rssFeed, nil
+}
+
+// get downloads a page, returning a FeedError for HTTP failures so they can
+// be told apart from network and parse errors
+func (c *Checker) get(ctx context.Context, url string, headers map[string]string) (*page, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid feed URL: %w", err)
+	}
+	req.Header.Set("User-Agent", c.parser.UserAgent)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 || isCloudflareChallenge(resp) {
+		return nil, httpStatusError(resp)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+	if err != nil {
+		return nil, err
+	}
+
+	return &page{body: body, url: resp.Request.URL}, nil
+}
+
+// findLatestItem picks the item to track: the first one matching the search
+// text for anime feeds, otherwise simply the newest item
+func findLatestItem(feed models.Feed, rssFeed *gofeed.Feed) (*gofeed.Item, error) {
+	for _, item := range rssFeed.Items {
+		if matchesSearch(feed, item) {
+			return item, nil
 		}
-		
-		if latestItem == nil {
-			log.Printf("✓ [%s] No matching anime found for search: %s\n", feed.Name, *feed.SearchText)
-			// Update last checked time even if no match found
-			lastChecked := time.Now().Format(time.RFC3339)
-			c.storage.UpdateFeed(feed.ID, map[string]interface{}{
-				"lastChecked": lastChecked,
-				"lastError":   nil,
-				"failCount":   0,
-			})
-			return nil
+	}
+
+	return nil, newFeedError(models.ErrorKindNoMatch, "no matching items found for search: %s", *feed.SearchText)
+}
+
+// matchesSearch reports whether an item passes the feed's search text,
+// which only applies to anime feeds
+func matchesSearch(feed models.Feed, item *gofeed.Item) bool {
+	if feed.Type != models.FeedTypeAnime || feed.SearchText == nil || *feed.SearchText == "" {
+		return true
+	}
+	return strings.Contains(strings.ToLower(item.Title), strings.ToLower(*feed.SearchText))
+}
+
+// processFeed looks for a new release for one feed in an already fetched
+// RSS feed
+func (c *Checker) processFeed(ctx context.Context, feed models.Feed, rssFeed *gofeed.Feed) error {
+	if feed.Type == models.FeedTypeAnime && feed.Preferences != nil {
+		if decision, ok := decideEpisode(feed, rssFeed, time.Now()); ok {
+			return c.processEpisode(ctx, feed, decision)
 		}
-	} else {
-		// For manga or anime without search, just get the first item
-		latestItem = rssFeed.Items[0]
+	}
+
+	latestItem, err := findLatestItem(feed, rssFeed)
+	if err != nil {
+		// A search with no match isn't a failure, the episode just isn't out yet
+		log.Printf("✓ [%s] %v\n", feed.Name, err)
+
+		feedErr := classifyError(err)
+		lastChecked := time.Now().Format(time.RFC3339)
+		updated, err := c.storage.UpdateFeed(feed.ID, map[string]interface{}{
+			"lastChecked": lastChecked,
+			"lastSuccess": lastChecked,
+			"lastError":   feedErr.Error(),
+			"errorKind":   feedErr.Kind,
+			"errorStatus": 0,
+			"failCount":   0,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save feed: %w", err)
+		}
+		c.publishChecked(ctx, feed, *updated)
+		return nil
 	}
 
 	latestChapter := latestItem.Title
@@ -135,21 +314,20 @@ func (c *Checker) checkFeedOnce(feed models.Feed) error {
 	// Check if this is a new chapter
 	if feed.LastChapter == nil {
 		log.Printf("✓ [%s] First check - storing: %s\n", feed.Name, latestChapter)
-	} else if *feed.LastChapter != latestChapter {
+	} else if isNewRelease(feed, latestItem) {
 		log.Printf("🆕 [%s] NEW %s FOUND!\n", feed.Name, 
 			map[bool]string{true: "EPISODE", false: "CHAPTER"}[feed.Type == models.FeedTypeAnime])
 		log.Printf("   Old: %s\n", *feed.LastChapter)
 		log.Printf("   New: %s\n", latestChapter)
 
-		// Send notification
-		err := c.notifier.SendNotification(feed.Name, latestChapter, link, string(feed.Type), feed.AnilistUrl, feed.Cover)
-		if err != nil {
-			log.Printf("⚠️ [%s] Failed to send notification: %v\n", feed.Name, err)
-		} else {
-			c.mu.Lock()
-			c.stats.NotificationsSent++
-			c.mu.Unlock()
-		}
+		// Subscribers send the notifications
+		c.events.Publish(ctx, events.ReleaseDetected{
+			Feed:   feed,
+			Title:  latestChapter,
+			Link:   link,
+			Info:   releaseInfo(latestItem),
+			Series: c.seriesOf(feed),
+		})
 	} else {
 		log.Printf("✓ [%s] No new %s (still: %s)\n", feed.Name,
 			map[bool]string{true: "episode", false: "chapter"}[feed.Type == models.FeedTypeAnime],
@@ -158,95 +336,106 @@ func (c *Checker) checkFeedOnce(feed models.Feed) error {
 
 	// Update feed
 	lastChecked := time.Now().Format(time.RFC3339)
-	c.storage.UpdateFeed(feed.ID, map[string]interface{}{
+	updates := map[string]interface{}{
 		"lastChecked": lastChecked,
+		"lastSuccess": lastChecked,
 		"lastChapter": latestChapter,
 		"lastError":   nil,
 		"failCount":   0,
-	})
+	}
+	if id := itemID(feed, latestItem); id != "" {
+		updates["lastItemId"] = id
+	}
+	updated, err := c.storage.UpdateFeed(feed.ID, updates)
+	if err != nil {
+		return fmt.Errorf("failed to save feed: %w", err)
+	}
 
+	c.publishChecked(ctx, feed, *updated)
 	return nil
 }
 
-func (c *Checker) CheckAll() {
-	feeds, err := c.storage.GetFeeds()
-	if err != nil {
-		log.Printf("❌ Error getting feeds: %v\n", err)
-		return
+// processEpisode applies the decision for an anime feed with release
+// preferences, notifying each episode once
+func (c *Checker) processEpisode(ctx context.Context, feed models.Feed, decision episodeDecision) error {
+	lastChecked := time.Now().Format(time.RFC3339)
+	updates := map[string]interface{}{
+		"lastChecked":    lastChecked,
+		"lastSuccess":    lastChecked,
+		"lastEpisode":    decision.episode,
+		"pendingEpisode": decision.pending,
+		"lastError":      nil,
+		"failCount":      0,
 	}
 
-	log.Println(strings.Repeat("=", 50))
-	log.Printf("🔍 Checking %d feed(s) at %s\n", len(feeds), time.Now().Format(time.RFC3339))
-	log.Println(strings.Repeat("=", 50))
-
-	lastCheckTime := time.Now().Format(time.RFC3339)
-	c.mu.Lock()
-	c.stats.LastCheckTime = &lastCheckTime
-	c.mu.Unlock()
+	switch decision.action {
+	case models.CheckActionFirstCheck:
+		log.Printf("✓ [%s] First check - storing episode %s: %s\n", feed.Name, decision.episode, decision.item.Title)
+		updates["lastChapter"] = decision.item.Title
+
+	case models.CheckActionWaiting:
+		log.Printf("⏳ [%s] Episode %s is out, waiting for a preferred release (best so far: %s)\n",
+			feed.Name, decision.episode, decision.item.Title)
+		// The last notified episode stays until this one is notified
+		delete(updates, "lastEpisode")
+
+	case models.CheckActionNew:
+		log.Printf("🆕 [%s] NEW EPISODE %s FOUND!\n", feed.Name, decision.episode)
+		log.Printf("   Release: %s\n", decision.item.Title)
+		c.events.Publish(ctx, events.ReleaseDetected{
+			Feed:   feed,
+			Title:  decision.item.Title,
+			Link:   decision.item.Link,
+			Info:   releaseInfo(decision.item),
+			Series: c.seriesOf(feed),
+		})
+		updates["lastChapter"] = decision.item.Title
+
+	default:
+		log.Printf("✓ [%s] No new episode (still: %s)\n", feed.Name, decision.episode)
+	}
 
-	for _, feed := range feeds {
-		c.CheckFeed(feed, 3)
-		time.Sleep(500 * time.Millisecond) // Small delay between checks
+	updated, err := c.storage.UpdateFeed(feed.ID, updates)
+	if err != nil {
+		return fmt.Errorf("failed to save feed: %w", err)
 	}
 
-	c.mu.RLock()
-	log.Println(strings.Repeat("=", 50))
-	log.Printf("📊 Check complete - Success: %d/%d, Notifications: %d\n",
-		c.stats.SuccessfulChecks, c.stats.TotalChecks, c.stats.NotificationsSent)
-	log.Println(strings.Repeat("=", 50))
-	c.mu.RUnlock()
+	c.publishChecked(ctx, feed, *updated)
+	return nil
 }
 
-func (c *Checker) TestFeed(feedID string) (map[string]interface{}, error) {
-	feeds, err := c.storage.GetFeeds()
+// seriesOf reads the series a feed belongs to for its release events
+func (c *Checker) seriesOf(feed models.Feed) *models.Series {
+	series, err := c.storage.GetSeries(feed.OwnerID, feed.SeriesID)
 	if err != nil {
-		return nil, err
-	}
-
-	var feed *models.Feed
-	for _, f := range feeds {
-		if f.ID == feedID {
-			feed = &f
-			break
-		}
+		return nil
 	}
+	return series
+}
 
-	if feed == nil {
-		return nil, fmt.Errorf("feed not found")
+// TestFeed sends a test notification for the latest item of a user's feed
+func (c *Checker) TestFeed(ctx context.Context, ownerID, feedID string) (map[string]interface{}, error) {
+	feed, err := c.storage.GetUserFeed(ownerID, feedID)
+	if err != nil {
+		return nil, err
 	}
 
-	rssFeed, err := c.parser.ParseURL(feed.RSSUrl)
+	rssFeed, err := c.fetchFeed(ctx, *feed)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse RSS: %w", err)
+		feedErr := classifyError(err)
+		if feedErr.Kind == models.ErrorKindEmpty {
+			return map[string]interface{}{"error": "No items found in feed", "errorKind": feedErr.Kind}, nil
+		}
+		return nil, err
 	}
 
-	if len(rssFeed.Items) == 0 {
-		return map[string]interface{}{"error": "No items found in RSS feed"}, nil
-	}
-
-	var latestItem *gofeed.Item
-	
-	// For anime feeds with search text, find matching item
-	if feed.Type == models.FeedTypeAnime && feed.SearchText != nil && *feed.SearchText != "" {
-		searchText := strings.ToLower(*feed.SearchText)
-		for _, item := range rssFeed.Items {
-			if strings.Contains(strings.ToLower(item.Title), searchText) {
-				latestItem = item
-				break
-			}
-		}
-		
-		if latestItem == nil {
-			return map[string]interface{}{
-				"error": fmt.Sprintf("No matching items found for search: %s", *feed.SearchText),
-			}, nil
-		}
-	} else {
-		latestItem = rssFeed.Items[0]
+	latestItem, err := findLatestItem(*feed, rssFeed)
+	if err != nil {
+		return map[string]interface{}{"error": err.Error(), "errorKind": models.ErrorKindNoMatch}, nil
 	}
 
 	// Send test notification
-	err = c.notifier.SendTestNotification(feed.Name, latestItem.Title, latestItem.Link, string(feed.Type), feed.AnilistUrl, feed.Cover)
+	err = c.notifier.SendTestNotification(ctx, notifier.WithSeries(*feed, c.seriesOf(*feed)), latestItem.Title, latestItem.Link)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send test notification: %w", err)
 	}
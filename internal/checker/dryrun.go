@@ -0,0 +1,122 @@
+package checker
+
+import (
+	"context"
+	"time"
+
+	"shinkan-rebirth/internal/models"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// CheckFeedDryRun reports what checking a feed would do: the parsed items,
+// which of them match, which would be new and who would be notified.
+// Nothing is saved and no notifications are sent.
+func (c *Checker) CheckFeedDryRun(ctx context.Context, feed models.Feed, retries int) (models.CheckPreview, error) {
+	return c.dryRunSource(ctx, []models.Feed{feed}, retries)[0], ctx.Err()
+}
+
+// CheckAllDryRun is CheckFeedDryRun for a list of feeds, fetching each RSS
+// URL once. It doesn't run as a check job, so it can overlap a real check.
+func (c *Checker) CheckAllDryRun(ctx context.Context, feeds []models.Feed) ([]models.CheckPreview, error) {
+	previews := make([]models.CheckPreview, 0, len(feeds))
+	for _, group := range groupBySource(feeds) {
+		previews = append(previews, c.dryRunSource(ctx, group, 3)...)
+		if ctx.Err() != nil {
+			return previews, ctx.Err()
+		}
+	}
+	return previews, nil
+}
+
+func (c *Checker) dryRunSource(ctx context.Context, feeds []models.Feed, retries int) []models.CheckPreview {
+	c.inFlight.Add(1)
+	defer c.inFlight.Done()
+
+	previews := make([]models.CheckPreview, len(feeds))
+
+	rssFeed, err := c.fetchWithRetries(ctx, feeds, retries)
+	for i, feed := range feeds {
+		if err != nil {
+			feedErr := classifyError(err)
+			previews[i] = models.CheckPreview{
+				FeedID:    feed.ID,
+				FeedName:  feed.Name,
+				Action:    models.CheckActionFailed,
+				Items:     []models.PreviewItem{},
+				Notify:    []string{},
+				Error:     feedErr.Error(),
+				ErrorKind: feedErr.Kind,
+			}
+			continue
+		}
+		previews[i] = c.previewFeed(feed, rssFeed)
+	}
+	return previews
+}
+
+// previewFeed mirrors processFeed without its side effects
+func (c *Checker) previewFeed(feed models.Feed, rssFeed *gofeed.Feed) models.CheckPreview {
+	preview := models.CheckPreview{
+		FeedID:   feed.ID,
+		FeedName: feed.Name,
+		Items:    make([]models.PreviewItem, 0, len(rssFeed.Items)),
+		Notify:   []string{},
+	}
+
+	var decision episodeDecision
+	withPreferences := false
+	if feed.Type == models.FeedTypeAnime && feed.Preferences != nil {
+		decision, withPreferences = decideEpisode(feed, rssFeed, time.Now())
+	}
+
+	latestItem, err := findLatestItem(feed, rssFeed)
+	if withPreferences {
+		latestItem, err = decision.item, nil
+	}
+	latest := -1
+	for i, item := range rssFeed.Items {
+		previewItem := models.PreviewItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			Matches:     matchesSearch(feed, item),
+			ReleaseInfo: releaseInfo(item),
+		}
+		if item.PublishedParsed != nil {
+			previewItem.Date = item.PublishedParsed.Format(time.RFC3339)
+		}
+		if item == latestItem {
+			latest = i
+		}
+		preview.Items = append(preview.Items, previewItem)
+	}
+
+	if err != nil {
+		feedErr := classifyError(err)
+		preview.Action = models.CheckActionNoMatch
+		preview.Error = feedErr.Error()
+		preview.ErrorKind = feedErr.Kind
+		return preview
+	}
+
+	switch {
+	case withPreferences:
+		preview.Action = decision.action
+		if decision.action == models.CheckActionNew {
+			preview.Items[latest].New = true
+			preview.Notify = c.notifier.Channels(feed.OwnerID)
+		}
+	case feed.LastChapter == nil:
+		preview.Action = models.CheckActionFirstCheck
+	case isNewRelease(feed, latestItem):
+		preview.Action = models.CheckActionNew
+		preview.Items[latest].New = true
+		preview.Notify = c.notifier.Channels(feed.OwnerID)
+	default:
+		preview.Action = models.CheckActionUnchanged
+	}
+
+	tracked := preview.Items[latest]
+	preview.Latest = &tracked
+	return preview
+}
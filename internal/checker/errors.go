@@ -0,0 +1,136 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"shinkan-rebirth/internal/models"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// FeedError is a classified feed check failure
+type FeedError struct {
+	Kind       models.ErrorKind
+	StatusCode int
+	Err        error
+}
+
+func (e *FeedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FeedError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether retrying the check could succeed. Client errors,
+// Cloudflare challenges and malformed feeds won't fix themselves within a
+// few seconds, so they fail fast.
+func (e *FeedError) Retryable() bool {
+	switch e.Kind {
+	case models.ErrorKindDNS, models.ErrorKindTimeout, models.ErrorKindNetwork,
+		models.ErrorKindHTTP5xx, models.ErrorKindUnknown:
+		return true
+	case models.ErrorKindHTTP4xx:
+		// Request timeout and rate limiting are worth another attempt
+		return e.StatusCode == http.StatusRequestTimeout || e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+func newFeedError(kind models.ErrorKind, format string, args ...interface{}) *FeedError {
+	return &FeedError{Kind: kind, Err: fmt.Errorf(format, args...)}
+}
+
+// httpStatusError builds a FeedError for a non-2xx response
+func httpStatusError(resp *http.Response) *FeedError {
+	if isCloudflareChallenge(resp) {
+		return &FeedError{
+			Kind:       models.ErrorKindCloudflare,
+			StatusCode: resp.StatusCode,
+			Err:        fmt.Errorf("blocked by Cloudflare challenge (HTTP %d)", resp.StatusCode),
+		}
+	}
+
+	kind := models.ErrorKindHTTP5xx
+	if resp.StatusCode < 500 {
+		kind = models.ErrorKindHTTP4xx
+	}
+
+	return &FeedError{
+		Kind:       kind,
+		StatusCode: resp.StatusCode,
+		Err:        fmt.Errorf("server returned %s", resp.Status),
+	}
+}
+
+func isCloudflareChallenge(resp *http.Response) bool {
+	if resp.Header.Get("cf-mitigated") == "challenge" {
+		return true
+	}
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusServiceUnavailable {
+		return false
+	}
+	return strings.EqualFold(resp.Header.Get("Server"), "cloudflare")
+}
+
+// classifyError turns any error from a check into a FeedError
+func classifyError(err error) *FeedError {
+	var feedErr *FeedError
+	if errors.As(err, &feedErr) {
+		return feedErr
+	}
+
+	var httpErr gofeed.HTTPError
+	if errors.As(err, &httpErr) {
+		kind := models.ErrorKindHTTP5xx
+		if httpErr.StatusCode < 500 {
+			kind = models.ErrorKindHTTP4xx
+		}
+		return &FeedError{Kind: kind, StatusCode: httpErr.StatusCode, Err: err}
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		if dnsErr.IsTimeout {
+			return &FeedError{Kind: models.ErrorKindTimeout, Err: err}
+		}
+		return &FeedError{Kind: models.ErrorKindDNS, Err: err}
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &FeedError{Kind: models.ErrorKindTimeout, Err: err}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return &FeedError{Kind: models.ErrorKindTimeout, Err: err}
+	}
+
+	var unknownAuthErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certInvalidErr x509.CertificateInvalidError
+	var recordErr tls.RecordHeaderError
+	if errors.As(err, &unknownAuthErr) || errors.As(err, &hostnameErr) ||
+		errors.As(err, &certInvalidErr) || errors.As(err, &recordErr) || strings.Contains(err.Error(), "tls: ") {
+		return &FeedError{Kind: models.ErrorKindTLS, Err: err}
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return &FeedError{Kind: models.ErrorKindNetwork, Err: err}
+	}
+
+	if errors.Is(err, gofeed.ErrFeedTypeNotDetected) {
+		return &FeedError{Kind: models.ErrorKindParse, Err: err}
+	}
+
+	return &FeedError{Kind: models.ErrorKindUnknown, Err: err}
+}
@@ -0,0 +1,199 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"shinkan-rebirth/internal/models"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/mmcdole/gofeed"
+)
+
+// Date layouts tried when an HTML source has no date format
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"Jan 02, 2006",
+	"2 January 2006",
+	"02 Jan 2006",
+	"2 Jan 2006",
+	time.RFC1123,
+	time.RFC1123Z,
+}
+
+// Dates like "3 days ago" that chapter lists often show
+var relativeDate = regexp.MustCompile(`^(\d+|an?|one)\s+(second|sec|minute|min|hour|day|week|month|year)s?\s+ago$`)
+
+var relativeUnits = map[string]time.Duration{
+	"second": time.Second,
+	"sec":    time.Second,
+	"minute": time.Minute,
+	"min":    time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+	"week":   7 * 24 * time.Hour,
+	"month":  30 * 24 * time.Hour,
+	"year":   365 * 24 * time.Hour,
+}
+
+// htmlSource reads releases from a web page with CSS selectors
+type htmlSource struct {
+	get    getFunc
+	config models.HTMLSource
+}
+
+func validateHTML(config *models.HTMLSource) error {
+	if config == nil || strings.TrimSpace(config.Item) == "" {
+		return newFeedError(models.ErrorKindConfig, "HTML source needs an item selector")
+	}
+
+	selectors := map[string]string{
+		"item":  config.Item,
+		"title": config.Title,
+		"link":  config.Link,
+		"date":  config.Date,
+	}
+	for name, selector := range selectors {
+		if selector == "" {
+			continue
+		}
+		if _, err := cascadia.Compile(selector); err != nil {
+			return newFeedError(models.ErrorKindConfig, "invalid %s selector %q: %v", name, selector, err)
+		}
+	}
+	return nil
+}
+
+func (s *htmlSource) Fetch(ctx context.Context, url string) (*gofeed.Feed, error) {
+	page, err := s.get(ctx, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page.body))
+	if err != nil {
+		return nil, &FeedError{Kind: models.ErrorKindParse, Err: fmt.Errorf("failed to parse HTML: %w", err)}
+	}
+
+	feed := &gofeed.Feed{
+		Title:    text(doc.Find("title").First()),
+		Link:     page.url.String(),
+		FeedType: string(models.SourceHTML),
+	}
+
+	now := time.Now()
+	doc.Find(s.config.Item).Each(func(_ int, item *goquery.Selection) {
+		title := text(item)
+		if s.config.Title != "" {
+			title = text(item.Find(s.config.Title).First())
+		}
+		if title == "" {
+			return
+		}
+
+		feedItem := &gofeed.Item{Title: title, Link: s.link(item, page.url)}
+		if s.config.Date != "" {
+			raw := dateText(item.Find(s.config.Date).First())
+			if date, ok := parseDate(raw, s.config.DateFormat, now); ok {
+				feedItem.Published = raw
+				feedItem.PublishedParsed = &date
+			}
+		}
+		feed.Items = append(feed.Items, feedItem)
+	})
+
+	if s.config.Reverse {
+		for i, j := 0, len(feed.Items)-1; i < j; i, j = i+1, j-1 {
+			feed.Items[i], feed.Items[j] = feed.Items[j], feed.Items[i]
+		}
+	}
+
+	return feed, nil
+}
+
+// link finds an item's link: the link selector, the item itself if it is a
+// link, otherwise the first link inside it
+func (s *htmlSource) link(item *goquery.Selection, base *url.URL) string {
+	selection := item
+	if s.config.Link != "" {
+		selection = item.Find(s.config.Link).First()
+	} else if !item.Is("a") {
+		selection = item.Find("a[href]").First()
+	}
+
+	href, ok := selection.Attr("href")
+	if !ok {
+		return ""
+	}
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return ""
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// text returns the text of a selection with whitespace collapsed
+func text(selection *goquery.Selection) string {
+	return strings.Join(strings.Fields(selection.Text()), " ")
+}
+
+// dateText prefers machine-readable dates in attributes over the text
+func dateText(selection *goquery.Selection) string {
+	for _, attr := range []string{"datetime", "data-date", "title"} {
+		if value, ok := selection.Attr(attr); ok && strings.TrimSpace(value) != "" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return text(selection)
+}
+
+// parseDate parses an absolute or relative date. Relative dates are
+// counted back from now.
+func parseDate(raw, layout string, now time.Time) (time.Time, bool) {
+	if raw == "" {
+		return time.Time{}, false
+	}
+
+	if layout != "" {
+		date, err := time.Parse(layout, raw)
+		return date, err == nil
+	}
+
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, raw); err == nil {
+			return date, true
+		}
+	}
+
+	lower := strings.ToLower(raw)
+	switch lower {
+	case "just now", "now", "today":
+		return now, true
+	case "yesterday":
+		return now.AddDate(0, 0, -1), true
+	}
+
+	if m := relativeDate.FindStringSubmatch(lower); m != nil {
+		count, err := strconv.Atoi(m[1])
+		if err != nil {
+			count = 1 // "a day ago", "one hour ago"
+		}
+		return now.Add(-time.Duration(count) * relativeUnits[m[2]]), true
+	}
+
+	return time.Time{}, false
+}
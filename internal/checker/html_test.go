@@ -0,0 +1,132 @@
+package checker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"shinkan-rebirth/internal/models"
+)
+
+const chapterList = `<html><head><title> Example Manga </title></head><body>
+<ul class="chapters">
+	<li class="chapter">
+		<a class="name" href="/manga/example/chapter-3">  Chapter 3:
+			The Return </a>
+		<time datetime="2025-12-30T10:00:00Z">3 days ago</time>
+	</li>
+	<li class="chapter">
+		<a class="name" href="chapter-2">Chapter 2</a>
+		<span class="date" data-date="2025-12-20">Dec 20</span>
+	</li>
+	<li class="chapter">
+		<a class="name" href="https://mirror.example/c/1">Chapter 1</a>
+		<span class="date">Jan 2, 2025</span>
+	</li>
+	<li class="chapter"><span class="date">Coming soon</span></li>
+</ul>
+</body></html>`
+
+func TestHTMLSourceFetch(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		config  models.HTMLSource
+		titles  []string
+		links   []string
+		hasDate []bool
+	}{
+		{
+			name:    "item, title, link and date selectors",
+			config:  models.HTMLSource{Item: "li.chapter", Title: "a.name", Link: "a.name", Date: "time, .date"},
+			titles:  []string{"Chapter 3: The Return", "Chapter 2", "Chapter 1"},
+			links:   []string{"https://site.example/manga/example/chapter-3", "https://site.example/manga/example/chapter-2", "https://mirror.example/c/1"},
+			hasDate: []bool{true, true, true},
+		},
+		{
+			name:    "items are the links, oldest first",
+			config:  models.HTMLSource{Item: "a.name", Reverse: true},
+			titles:  []string{"Chapter 1", "Chapter 2", "Chapter 3: The Return"},
+			links:   []string{"https://mirror.example/c/1", "https://site.example/manga/example/chapter-2", "https://site.example/manga/example/chapter-3"},
+			hasDate: []bool{false, false, false},
+		},
+		{
+			name:    "first link inside the item",
+			config:  models.HTMLSource{Item: "li.chapter", Date: ".date", DateFormat: "2006-01-02"},
+			titles:  []string{"Chapter 3: The Return 3 days ago", "Chapter 2 Dec 20", "Chapter 1 Jan 2, 2025", "Coming soon"},
+			links:   []string{"https://site.example/manga/example/chapter-3", "https://site.example/manga/example/chapter-2", "https://mirror.example/c/1", ""},
+			hasDate: []bool{false, true, false, false},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &htmlSource{get: staticPage(t, "https://site.example/manga/example/", chapterList), config: tc.config}
+			feed, err := s.Fetch(context.Background(), "https://site.example/manga/example/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if feed.Title != "Example Manga" {
+				t.Errorf("feed title = %q", feed.Title)
+			}
+			if len(feed.Items) != len(tc.titles) {
+				t.Fatalf("got %d items, want %d", len(feed.Items), len(tc.titles))
+			}
+			for i, item := range feed.Items {
+				if item.Title != tc.titles[i] || item.Link != tc.links[i] || (item.PublishedParsed != nil) != tc.hasDate[i] {
+					t.Errorf("item %d = %q %q date %v", i, item.Title, item.Link, item.PublishedParsed)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateHTML(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config *models.HTMLSource
+		valid  bool
+	}{
+		{"no config", nil, false},
+		{"no item selector", &models.HTMLSource{Title: "a"}, false},
+		{"item only", &models.HTMLSource{Item: "li.chapter"}, true},
+		{"all selectors", &models.HTMLSource{Item: "li", Title: "a.name", Link: "a[href]", Date: "time"}, true},
+		{"invalid item", &models.HTMLSource{Item: "li[["}, false},
+		{"invalid date", &models.HTMLSource{Item: "li", Date: "::nope"}, false},
+	} {
+		err := validateHTML(tc.config)
+		if (err == nil) != tc.valid {
+			t.Errorf("%s: validateHTML = %v, want valid %v", tc.name, err, tc.valid)
+		}
+		if err != nil && classifyError(err).Kind != models.ErrorKindConfig {
+			t.Errorf("%s: error kind = %s, want config", tc.name, classifyError(err).Kind)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	for _, tc := range []struct {
+		raw, layout string
+		want        time.Time
+		ok          bool
+	}{
+		{raw: "2025-12-30T10:00:00Z", want: time.Date(2025, 12, 30, 10, 0, 0, 0, time.UTC), ok: true},
+		{raw: "2025-12-20", want: time.Date(2025, 12, 20, 0, 0, 0, 0, time.UTC), ok: true},
+		{raw: "Jan 2, 2025", want: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), ok: true},
+		{raw: "2 January 2025", want: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), ok: true},
+		{raw: "02.01.2025", layout: "02.01.2006", want: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), ok: true},
+		{raw: "2025-01-02", layout: "02.01.2006"},
+		{raw: "Just now", want: now, ok: true},
+		{raw: "yesterday", want: now.Add(-day), ok: true},
+		{raw: "3 days ago", want: now.Add(-3 * day), ok: true},
+		{raw: "an hour ago", want: now.Add(-time.Hour), ok: true},
+		{raw: "2 weeks ago", want: now.Add(-14 * day), ok: true},
+		{raw: "Coming soon"},
+		{raw: ""},
+	} {
+		got, ok := parseDate(tc.raw, tc.layout, now)
+		if ok != tc.ok || (ok && !got.Equal(tc.want)) {
+			t.Errorf("parseDate(%q, %q) = %v, %v, want %v, %v", tc.raw, tc.layout, got, ok, tc.want, tc.ok)
+		}
+	}
+}
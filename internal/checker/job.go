@@ -0,0 +1,297 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"shinkan-rebirth/internal/events"
+	"shinkan-rebirth/internal/models"
+	"shinkan-rebirth/internal/storage"
+)
+
+// What started a check run
+const (
+	TriggerStartup  = "startup"
+	TriggerSchedule = "schedule"
+	TriggerDiscord  = "discord"
+	TriggerAPI      = "api"
+	TriggerCLI      = "cli"
+)
+
+type checkJob struct {
+	models.CheckJob
+	done    chan struct{}
+	results map[string]error // Check result per feed ID, guarded by jobMu
+}
+
+// StartCheckAll starts a check of all feeds in the background. If a check is
+// already running no new one is started; the running job is returned instead
+// and started is false.
+func (c *Checker) StartCheckAll(ctx context.Context, trigger string) (job models.CheckJob, started bool) {
+	j, started := c.startJob(ctx, trigger, "")
+	return c.snapshot(j), started
+}
+
+// CheckAll checks every feed and waits for the run to finish. Overlapping
+// calls coalesce into the job that is already running.
+func (c *Checker) CheckAll(ctx context.Context, trigger string) {
+	j, started := c.startJob(ctx, trigger, "")
+	if !started {
+		log.Printf("⏳ Check already running (job %s), skipping %s trigger\n", j.ID, trigger)
+	}
+
+	select {
+	case <-j.done:
+	case <-ctx.Done():
+	}
+}
+
+// CheckOne checks a single feed as a job of its own and returns the result
+// of the check. A running check of all feeds covers the feed, so it waits
+// for that job instead of checking the feed twice.
+func (c *Checker) CheckOne(ctx context.Context, feedID, trigger string) error {
+	for attempt := 0; attempt < 2; attempt++ {
+		j, started := c.startJob(ctx, trigger, feedID)
+		if !started {
+			log.Printf("⏳ Check already running (job %s), waiting for it to check the feed\n", j.ID)
+		}
+
+		select {
+		case <-j.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		c.jobMu.Lock()
+		err, checked := j.results[feedID]
+		job := j.CheckJob
+		c.jobMu.Unlock()
+
+		switch {
+		case checked:
+			return err
+		case job.Status == models.JobFailed:
+			return errors.New(job.Error)
+		case job.Status == models.JobCancelled:
+			return context.Canceled
+		case started:
+			return storage.ErrFeedNotFound
+		}
+		// The running job started before the feed was added, check it again
+	}
+	return storage.ErrFeedNotFound
+}
+
+// CheckStatus returns the running job, or the last one if none is running.
+// ok is false if no check has run yet.
+func (c *Checker) CheckStatus() (job models.CheckJob, ok bool) {
+	c.jobMu.Lock()
+	defer c.jobMu.Unlock()
+
+	if c.job == nil {
+		return models.CheckJob{}, false
+	}
+	return c.job.CheckJob, true
+}
+
+// WatchJob sends a snapshot of the job every interval until it finishes. The
+// final state is sent before the channel is closed, unless ctx is cancelled
+// first because nobody is reading anymore.
+func (c *Checker) WatchJob(ctx context.Context, id string, interval time.Duration) <-chan models.CheckJob {
+	updates := make(chan models.CheckJob)
+
+	c.jobMu.Lock()
+	j := c.job
+	c.jobMu.Unlock()
+
+	go func() {
+		defer close(updates)
+		if j == nil || j.ID != id {
+			return
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		send := func() bool {
+			select {
+			case updates <- c.snapshot(j):
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for {
+			select {
+			case <-j.done:
+				send()
+				return
+			case <-ticker.C:
+				if !send() {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return updates
+}
+
+func (c *Checker) snapshot(j *checkJob) models.CheckJob {
+	c.jobMu.Lock()
+	defer c.jobMu.Unlock()
+	return j.CheckJob
+}
+
+func (c *Checker) updateJob(j *checkJob, update func(job *models.CheckJob)) {
+	c.jobMu.Lock()
+	defer c.jobMu.Unlock()
+	update(&j.CheckJob)
+}
+
+// startJob starts a check of every feed, or of one feed if feedID is set.
+// A running job checking all feeds or the same feed is returned instead.
+// Any other running job is waited for, so two jobs never update the same
+// feed at once.
+func (c *Checker) startJob(ctx context.Context, trigger, feedID string) (*checkJob, bool) {
+	c.jobMu.Lock()
+	defer c.jobMu.Unlock()
+
+	previous := c.job
+	if previous != nil && previous.Status == models.JobRunning && (previous.FeedID == "" || previous.FeedID == feedID) {
+		return previous, false
+	}
+
+	j := &checkJob{
+		CheckJob: models.CheckJob{
+			ID:        fmt.Sprintf("%d", time.Now().UnixNano()),
+			Trigger:   trigger,
+			FeedID:    feedID,
+			Status:    models.JobRunning,
+			StartedAt: time.Now().Format(time.RFC3339),
+		},
+		done:    make(chan struct{}),
+		results: make(map[string]error),
+	}
+	c.job = j
+
+	c.inFlight.Add(1)
+	go func() {
+		defer c.inFlight.Done()
+		if previous != nil {
+			select {
+			case <-previous.done:
+			case <-ctx.Done():
+			}
+		}
+		c.runJob(ctx, j)
+	}()
+
+	return j, true
+}
+
+func (c *Checker) runJob(ctx context.Context, j *checkJob) {
+	defer close(j.done)
+
+	c.events.Publish(ctx, events.CheckStarted{Job: c.snapshot(j)})
+
+	finish := func(status models.JobStatus, errMsg string) {
+		finishedAt := time.Now().Format(time.RFC3339)
+		c.updateJob(j, func(job *models.CheckJob) {
+			job.Status = status
+			job.Error = errMsg
+			job.CurrentFeed = ""
+			job.FinishedAt = &finishedAt
+		})
+		c.events.Publish(ctx, events.CheckFinished{Job: c.snapshot(j)})
+	}
+
+	feeds, err := c.storage.GetFeeds()
+	if err == nil && j.FeedID != "" {
+		feeds, err = findFeed(feeds, j.FeedID)
+	}
+	if err != nil {
+		log.Printf("❌ Error getting feeds: %v\n", err)
+		finish(models.JobFailed, err.Error())
+		return
+	}
+
+	c.updateJob(j, func(job *models.CheckJob) { job.Total = len(feeds) })
+
+	log.Println(strings.Repeat("=", 50))
+	log.Printf("🔍 Checking %d feed(s) at %s (%s)\n", len(feeds), time.Now().Format(time.RFC3339), j.Trigger)
+	log.Println(strings.Repeat("=", 50))
+
+	for _, group := range groupBySource(feeds) {
+		name := group[0].Name
+		c.updateJob(j, func(job *models.CheckJob) { job.CurrentFeed = name })
+
+		errs := c.checkSource(ctx, group, 3)
+		if ctx.Err() != nil {
+			break
+		}
+
+		c.updateJob(j, func(job *models.CheckJob) {
+			for i, err := range errs {
+				j.results[group[i].ID] = err
+				job.Done++
+				if err != nil {
+					job.Failed++
+				}
+			}
+		})
+
+		if err := sleep(ctx, 500*time.Millisecond); err != nil { // Small delay between checks
+			break
+		}
+	}
+
+	if ctx.Err() != nil {
+		log.Println("🛑 Check cancelled")
+		finish(models.JobCancelled, "")
+		return
+	}
+
+	finish(models.JobFinished, "")
+
+	job := c.snapshot(j)
+	log.Println(strings.Repeat("=", 50))
+	log.Printf("📊 Check complete - Success: %d/%d\n", job.Done-job.Failed, job.Total)
+	log.Println(strings.Repeat("=", 50))
+}
+
+// findFeed returns just the feed with the given ID
+func findFeed(feeds []models.Feed, id string) ([]models.Feed, error) {
+	for _, feed := range feeds {
+		if feed.ID == id {
+			return []models.Feed{feed}, nil
+		}
+	}
+	return nil, storage.ErrFeedNotFound
+}
+
+// groupBySource groups feeds read from the same source, keeping the
+// original order of first appearance
+func groupBySource(feeds []models.Feed) [][]models.Feed {
+	index := make(map[string]int)
+	groups := make([][]models.Feed, 0, len(feeds))
+
+	for _, feed := range feeds {
+		key := sourceKey(feed)
+		if i, ok := index[key]; ok {
+			groups[i] = append(groups[i], feed)
+			continue
+		}
+		index[key] = len(groups)
+		groups = append(groups, []models.Feed{feed})
+	}
+
+	return groups
+}
@@ -0,0 +1,168 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"shinkan-rebirth/internal/models"
+	"shinkan-rebirth/internal/storage"
+)
+
+// slowRSS serves a one-item feed per path, holding every request until
+// release is called, and remembers how many were in flight at once
+type slowRSS struct {
+	*httptest.Server
+	gate chan struct{}
+
+	mu          sync.Mutex
+	requests    map[string]int
+	inFlight    int
+	maxInFlight int
+}
+
+func newSlowRSS(t *testing.T) *slowRSS {
+	s := &slowRSS{gate: make(chan struct{}), requests: make(map[string]int)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests[r.URL.Path]++
+		s.inFlight++
+		if s.inFlight > s.maxInFlight {
+			s.maxInFlight = s.inFlight
+		}
+		s.mu.Unlock()
+
+		<-s.gate
+
+		s.mu.Lock()
+		s.inFlight--
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprintf(w, `<rss version="2.0"><channel><title>Test</title><item><title>%s Chapter 1</title><link>https://example.com%s/1</link></item></channel></rss>`, r.URL.Path, r.URL.Path)
+	}))
+	t.Cleanup(func() {
+		s.release()
+		s.Close()
+	})
+	return s
+}
+
+func (s *slowRSS) release() {
+	select {
+	case <-s.gate:
+	default:
+		close(s.gate)
+	}
+}
+
+func (s *slowRSS) requested(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+func addTestFeed(t *testing.T, store *storage.Storage, url string) models.Feed {
+	feed, err := store.AddFeed(models.Feed{Name: url, RSSUrl: url, Type: models.FeedTypeManga})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return feed
+}
+
+// waitFor polls until cond holds, failing the test after a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCheckOneJoinsRunningCheckAll(t *testing.T) {
+	rss := newSlowRSS(t)
+	c, store, _ := newTestChecker(t, "")
+	feed := addTestFeed(t, store, rss.URL+"/a")
+	ctx := context.Background()
+
+	all, started := c.StartCheckAll(ctx, TriggerAPI)
+	if !started {
+		t.Fatal("check of all feeds not started")
+	}
+	waitFor(t, "the check to fetch the feed", func() bool { return rss.requested("/a") == 1 })
+
+	result := make(chan error, 1)
+	go func() { result <- c.CheckOne(ctx, feed.ID, TriggerAPI) }()
+
+	if job, _ := c.CheckStatus(); job.ID != all.ID || job.FeedID != "" {
+		t.Errorf("status = job %s for feed %q, want the running check of all feeds", job.ID, job.FeedID)
+	}
+
+	rss.release()
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+	if got := rss.requested("/a"); got != 1 {
+		t.Errorf("feed fetched %d times, want once by the running check", got)
+	}
+	if job, _ := c.CheckStatus(); job.ID != all.ID || job.Status != models.JobFinished {
+		t.Errorf("status = %+v, want the finished check of all feeds", job)
+	}
+}
+
+func TestCheckOneQueuesBehindAnotherFeed(t *testing.T) {
+	rss := newSlowRSS(t)
+	c, store, _ := newTestChecker(t, "")
+	first := addTestFeed(t, store, rss.URL+"/a")
+	second := addTestFeed(t, store, rss.URL+"/b")
+	ctx := context.Background()
+
+	results := make(chan error, 2)
+	go func() { results <- c.CheckOne(ctx, first.ID, TriggerAPI) }()
+	waitFor(t, "the first feed to be fetched", func() bool { return rss.requested("/a") == 1 })
+
+	go func() { results <- c.CheckOne(ctx, second.ID, TriggerCLI) }()
+	waitFor(t, "the second job to start", func() bool {
+		job, _ := c.CheckStatus()
+		return job.FeedID == second.ID
+	})
+	if got := rss.requested("/b"); got != 0 {
+		t.Errorf("second feed fetched while the first check was running")
+	}
+
+	rss.release()
+	for i := 0; i < 2; i++ {
+		if err := <-results; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if rss.maxInFlight != 1 {
+		t.Errorf("%d checks ran at once, want 1", rss.maxInFlight)
+	}
+
+	job, _ := c.CheckStatus()
+	if job.FeedID != second.ID || job.Total != 1 || job.Done != 1 || job.Status != models.JobFinished {
+		t.Errorf("status = %+v, want the finished check of the second feed", job)
+	}
+	if updated, err := store.GetUserFeed(models.DefaultUserID, second.ID); err != nil || updated.LastChapter == nil {
+		t.Errorf("second feed not updated: %+v, %v", updated, err)
+	}
+}
+
+func TestCheckOneUnknownFeed(t *testing.T) {
+	c, _, _ := newTestChecker(t, "")
+
+	if err := c.CheckOne(context.Background(), "missing", TriggerAPI); err == nil {
+		t.Fatal("checked a feed that doesn't exist")
+	}
+	if job, _ := c.CheckStatus(); job.Status != models.JobFailed {
+		t.Errorf("status = %s, want failed", job.Status)
+	}
+}
@@ -0,0 +1,310 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"shinkan-rebirth/internal/models"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// Placeholders in a JSON source template, like {attributes.chapter}
+var templateField = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// jsonSource reads releases from a JSON API with paths
+type jsonSource struct {
+	get    getFunc
+	config models.JSONSource
+}
+
+// pathStep is one key or array index of a path
+type pathStep struct {
+	key   string
+	index int
+	isKey bool
+}
+
+func validateJSON(config *models.JSONSource) error {
+	if config == nil || strings.TrimSpace(config.Title) == "" {
+		return newFeedError(models.ErrorKindConfig, "JSON source needs a title path")
+	}
+
+	fields := map[string]string{
+		"items": config.Items,
+		"title": config.Title,
+		"link":  config.Link,
+		"id":    config.ID,
+		"date":  config.Date,
+	}
+	for name, field := range fields {
+		for _, path := range fieldPaths(field) {
+			if _, err := parsePath(path); err != nil {
+				return newFeedError(models.ErrorKindConfig, "invalid %s path %q: %v", name, path, err)
+			}
+		}
+	}
+
+	for name := range config.Headers {
+		if strings.TrimSpace(name) == "" || strings.ContainsAny(name, ": \r\n") {
+			return newFeedError(models.ErrorKindConfig, "invalid header name %q", name)
+		}
+	}
+	return nil
+}
+
+func (s *jsonSource) Fetch(ctx context.Context, url string) (*gofeed.Feed, error) {
+	headers := map[string]string{"Accept": "application/json"}
+	for name, value := range s.config.Headers {
+		headers[name] = value
+	}
+
+	page, err := s.get(ctx, url, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(page.body, &doc); err != nil {
+		return nil, &FeedError{Kind: models.ErrorKindParse, Err: fmt.Errorf("failed to parse JSON: %w", err)}
+	}
+
+	list, ok := lookup(doc, s.config.Items)
+	if !ok {
+		return nil, newFeedError(models.ErrorKindParse, "items path %q not found in response", s.config.Items)
+	}
+	items, ok := list.([]interface{})
+	if !ok {
+		return nil, newFeedError(models.ErrorKindParse, "items path %q is not a list", s.config.Items)
+	}
+
+	feed := &gofeed.Feed{
+		Link:     page.url.String(),
+		FeedType: string(models.SourceJSON),
+	}
+
+	now := time.Now()
+	for _, item := range items {
+		title := strings.Join(strings.Fields(s.render(item, s.config.Title)), " ")
+		if title == "" {
+			continue
+		}
+
+		feedItem := &gofeed.Item{
+			Title: title,
+			GUID:  s.render(item, s.config.ID),
+			Link:  s.link(item, page.url),
+		}
+		if s.config.Date != "" {
+			if value, ok := lookup(item, s.config.Date); ok {
+				if date, ok := jsonDate(value, s.config.DateFormat, now); ok {
+					feedItem.Published = scalar(value)
+					feedItem.PublishedParsed = &date
+				}
+			}
+		}
+		feed.Items = append(feed.Items, feedItem)
+	}
+
+	if s.config.Reverse {
+		for i, j := 0, len(feed.Items)-1; i < j; i, j = i+1, j-1 {
+			feed.Items[i], feed.Items[j] = feed.Items[j], feed.Items[i]
+		}
+	}
+
+	return feed, nil
+}
+
+// itemID returns the ID of a release read by a JSON source with an ID
+// path, and nothing for other sources
+func itemID(feed models.Feed, item *gofeed.Item) string {
+	if feed.SourceKind() != models.SourceJSON || feed.Source.JSON == nil || feed.Source.JSON.ID == "" {
+		return ""
+	}
+	return item.GUID
+}
+
+// isNewRelease reports whether the latest item is not the last release
+// seen. Releases with an ID are compared by it, so a retitled release isn't
+// new and releases sharing a title are told apart. Titles are compared
+// until a check has recorded an ID.
+func isNewRelease(feed models.Feed, item *gofeed.Item) bool {
+	if id := itemID(feed, item); id != "" && feed.LastItemID != nil {
+		return id != *feed.LastItemID
+	}
+	return feed.LastChapter == nil || *feed.LastChapter != item.Title
+}
+
+// render returns the value at a path, or fills in a template. {id} in a
+// template is the item's ID.
+func (s *jsonSource) render(item interface{}, field string) string {
+	if field == "" {
+		return ""
+	}
+	if !strings.Contains(field, "{") {
+		value, _ := lookup(item, field)
+		return scalar(value)
+	}
+
+	return templateField.ReplaceAllStringFunc(field, func(placeholder string) string {
+		path := placeholder[1 : len(placeholder)-1]
+		if path == "id" && s.config.ID != "" {
+			path = s.config.ID
+		}
+		value, _ := lookup(item, path)
+		return scalar(value)
+	})
+}
+
+// link renders an item's link and resolves it against the API URL
+func (s *jsonSource) link(item interface{}, base *url.URL) string {
+	link := strings.TrimSpace(s.render(item, s.config.Link))
+	if link == "" {
+		return ""
+	}
+	ref, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// fieldPaths returns the paths used by a field, which is a path itself
+// unless it is a template
+func fieldPaths(field string) []string {
+	if !strings.Contains(field, "{") {
+		return []string{field}
+	}
+	var paths []string
+	for _, m := range templateField.FindAllStringSubmatch(field, -1) {
+		if m[1] != "id" {
+			paths = append(paths, m[1])
+		}
+	}
+	return paths
+}
+
+// parsePath splits a path like $.data[0].attributes["title"] into steps.
+// The leading $ or . is optional and [*] is ignored, so JSONPath and jq
+// style paths to a list both work.
+func parsePath(path string) ([]pathStep, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+
+	var steps []pathStep
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '.':
+			i++
+
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("missing ] after position %d", i)
+			}
+			inner := strings.TrimSpace(path[i+1 : i+end])
+			i += end + 1
+
+			switch {
+			case inner == "*" || inner == "":
+				// The whole list
+			case len(inner) >= 2 && (inner[0] == '"' || inner[0] == '\'') && inner[len(inner)-1] == inner[0]:
+				steps = append(steps, pathStep{key: inner[1 : len(inner)-1], isKey: true})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index %q", inner)
+				}
+				steps = append(steps, pathStep{index: index})
+			}
+
+		default:
+			end := strings.IndexAny(path[i:], ".[")
+			if end < 0 {
+				end = len(path) - i
+			}
+			steps = append(steps, pathStep{key: path[i : i+end], isKey: true})
+			i += end
+		}
+	}
+	return steps, nil
+}
+
+// lookup returns the value at a path. Numeric keys also index lists, and
+// negative indexes count from the end.
+func lookup(value interface{}, path string) (interface{}, bool) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return nil, false
+	}
+
+	for _, step := range steps {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if !step.isKey {
+				return nil, false
+			}
+			next, ok := v[step.key]
+			if !ok {
+				return nil, false
+			}
+			value = next
+
+		case []interface{}:
+			index := step.index
+			if step.isKey {
+				n, err := strconv.Atoi(step.key)
+				if err != nil {
+					return nil, false
+				}
+				index = n
+			}
+			if index < 0 {
+				index += len(v)
+			}
+			if index < 0 || index >= len(v) {
+				return nil, false
+			}
+			value = v[index]
+
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// scalar formats a JSON value as text, nothing for objects and lists
+func scalar(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+// jsonDate parses a date as text, or as a Unix timestamp in seconds or
+// milliseconds
+func jsonDate(value interface{}, layout string, now time.Time) (time.Time, bool) {
+	if n, ok := value.(float64); ok {
+		if n <= 0 || math.IsInf(n, 0) {
+			return time.Time{}, false
+		}
+		if n > 1e12 {
+			return time.UnixMilli(int64(n)), true
+		}
+		return time.Unix(int64(n), 0), true
+	}
+	return parseDate(scalar(value), layout, now)
+}
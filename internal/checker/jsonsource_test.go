@@ -0,0 +1,237 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+
+	"shinkan-rebirth/internal/models"
+)
+
+// staticPage is a getFunc serving body as the page at rawURL
+func staticPage(t *testing.T, rawURL, body string) getFunc {
+	return func(ctx context.Context, _ string, _ map[string]string) (*page, error) {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &page{body: []byte(body), url: u}, nil
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	for _, tc := range []struct {
+		path string
+		want []pathStep
+		err  bool
+	}{
+		{path: "", want: nil},
+		{path: "data", want: []pathStep{{key: "data", isKey: true}}},
+		{path: "$.data[0].attributes.title", want: []pathStep{{key: "data", isKey: true}, {index: 0}, {key: "attributes", isKey: true}, {key: "title", isKey: true}}},
+		{path: `.links["self link"]`, want: []pathStep{{key: "links", isKey: true}, {key: "self link", isKey: true}}},
+		{path: "data[*]", want: []pathStep{{key: "data", isKey: true}}},
+		{path: "[-1]", want: []pathStep{{index: -1}}},
+		{path: "data[0", err: true},
+		{path: "data[first]", err: true},
+	} {
+		got, err := parsePath(tc.path)
+		if tc.err {
+			if err == nil {
+				t.Errorf("parsePath(%q) accepted", tc.path)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("parsePath(%q) = %+v, %v, want %+v", tc.path, got, err, tc.want)
+		}
+	}
+}
+
+func TestLookupAndRender(t *testing.T) {
+	item := map[string]interface{}{
+		"id":         "abc",
+		"number":     12.5,
+		"official":   true,
+		"attributes": map[string]interface{}{"chapter": "12", "title": "  The Gate  "},
+		"links":      []interface{}{"https://a.example/1", "https://a.example/2"},
+	}
+	s := &jsonSource{config: models.JSONSource{ID: "id"}}
+
+	for _, tc := range []struct{ field, want string }{
+		{"attributes.title", "The Gate"},
+		{"number", "12.5"},
+		{"official", "true"},
+		{"links[-1]", "https://a.example/2"},
+		{"links.0", "https://a.example/1"},
+		{"attributes", ""}, // Objects have no text
+		{"missing.path", ""},
+		{"Chapter {attributes.chapter}: {attributes.title}", "Chapter 12: The Gate"},
+		{"https://site.example/chapter/{id}", "https://site.example/chapter/abc"},
+		{"{missing} left empty", " left empty"},
+	} {
+		if got := s.render(item, tc.field); got != tc.want {
+			t.Errorf("render(%q) = %q, want %q", tc.field, got, tc.want)
+		}
+	}
+}
+
+func TestJSONSourceFetch(t *testing.T) {
+	const body = `{
+		"data": [
+			{"id": 2, "attributes": {"chapter": "2", "publishAt": 1767225600}, "path": "/read/2"},
+			{"id": 1, "attributes": {"chapter": "1", "publishAt": "2025-12-25T00:00:00Z"}, "path": "/read/1"},
+			{"id": 0, "attributes": {"chapter": ""}}
+		]
+	}`
+
+	for _, tc := range []struct {
+		name    string
+		config  models.JSONSource
+		titles  []string
+		links   []string
+		guids   []string
+		hasDate []bool
+	}{
+		{
+			name:    "template and relative link",
+			config:  models.JSONSource{Items: "data", Title: "Chapter {attributes.chapter}", Link: "path", ID: "id", Date: "attributes.publishAt"},
+			titles:  []string{"Chapter 2", "Chapter 1", "Chapter"},
+			links:   []string{"https://api.example/read/2", "https://api.example/read/1", ""},
+			guids:   []string{"2", "1", "0"},
+			hasDate: []bool{true, true, false},
+		},
+		{
+			name:    "path title skips empty items, oldest first",
+			config:  models.JSONSource{Items: "$.data[*]", Title: "attributes.chapter", Link: "https://site.example/c/{id}", Reverse: true},
+			titles:  []string{"1", "2"},
+			links:   []string{"https://site.example/c/1", "https://site.example/c/2"},
+			guids:   []string{"", ""},
+			hasDate: []bool{false, false},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &jsonSource{get: staticPage(t, "https://api.example/v1/feed", body), config: tc.config}
+			feed, err := s.Fetch(context.Background(), "https://api.example/v1/feed")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(feed.Items) != len(tc.titles) {
+				t.Fatalf("got %d items, want %d", len(feed.Items), len(tc.titles))
+			}
+			for i, item := range feed.Items {
+				if item.Title != tc.titles[i] || item.Link != tc.links[i] || item.GUID != tc.guids[i] || (item.PublishedParsed != nil) != tc.hasDate[i] {
+					t.Errorf("item %d = %q %q id %q date %v", i, item.Title, item.Link, item.GUID, item.PublishedParsed)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONSourceFetchErrors(t *testing.T) {
+	for _, tc := range []struct{ name, body, items string }{
+		{"not JSON", `<html>`, "data"},
+		{"missing items", `{"results": []}`, "data"},
+		{"items not a list", `{"data": {"id": 1}}`, "data"},
+	} {
+		s := &jsonSource{get: staticPage(t, "https://api.example/", tc.body), config: models.JSONSource{Items: tc.items, Title: "id"}}
+		_, err := s.Fetch(context.Background(), "https://api.example/")
+		if feedErr := classifyError(err); feedErr.Kind != models.ErrorKindParse {
+			t.Errorf("%s: err = %v (%s), want a parse error", tc.name, err, feedErr.Kind)
+		}
+	}
+}
+
+func TestJSONSourceDetectsReleasesByID(t *testing.T) {
+	var mu sync.Mutex
+	body := `[{"id": "a", "title": "Chapter 1"}]`
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(api.Close)
+	serve := func(b string) {
+		mu.Lock()
+		defer mu.Unlock()
+		body = b
+	}
+
+	c, store, publisher := newTestChecker(t, "")
+	ctx := context.Background()
+	feed, err := store.AddFeed(models.Feed{
+		Name:   "API Manga",
+		RSSUrl: api.URL,
+		Type:   models.FeedTypeManga,
+		Source: &models.Source{Kind: models.SourceJSON, JSON: &models.JSONSource{Title: "title", ID: "id"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := func() models.Feed {
+		t.Helper()
+		current, err := store.GetUserFeed(models.DefaultUserID, feed.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.CheckFeed(ctx, *current, 1); err != nil {
+			t.Fatal(err)
+		}
+		updated, err := store.GetUserFeed(models.DefaultUserID, feed.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *updated
+	}
+
+	if updated := check(); updated.LastItemID == nil || *updated.LastItemID != "a" {
+		t.Fatalf("first check stored id %v, want a", updated.LastItemID)
+	}
+
+	// Retitled, but the same release
+	serve(`[{"id": "a", "title": "Chapter 1 - The Gate"}]`)
+	if updated := check(); *updated.LastChapter != "Chapter 1 - The Gate" {
+		t.Errorf("last chapter = %q, want the new title stored", *updated.LastChapter)
+	}
+	if got := len(publisher.releases()); got != 0 {
+		t.Fatalf("a retitled release was published %d times", got)
+	}
+
+	// A new release with the same title
+	serve(`[{"id": "b", "title": "Chapter 1 - The Gate"}]`)
+	check()
+	releases := publisher.releases()
+	if len(releases) != 1 || releases[0].Title != "Chapter 1 - The Gate" {
+		t.Errorf("releases = %+v, want the new release b", releases)
+	}
+}
+
+func TestIsNewRelease(t *testing.T) {
+	text := func(s string) *string { return &s }
+	withID := &models.Source{Kind: models.SourceJSON, JSON: &models.JSONSource{Title: "title", ID: "id"}}
+
+	for _, tc := range []struct {
+		name string
+		feed models.Feed
+		item gofeed.Item
+		want bool
+	}{
+		{"RSS compares titles", models.Feed{LastChapter: text("Chapter 1")}, gofeed.Item{Title: "Chapter 1", GUID: "x"}, false},
+		{"RSS ignores IDs", models.Feed{LastChapter: text("Chapter 1"), LastItemID: text("y")}, gofeed.Item{Title: "Chapter 1", GUID: "x"}, false},
+		{"no ID recorded yet", models.Feed{LastChapter: text("Chapter 1"), Source: withID}, gofeed.Item{Title: "Chapter 2", GUID: "b"}, true},
+		{"same ID, new title", models.Feed{LastChapter: text("Chapter 1"), LastItemID: text("a"), Source: withID}, gofeed.Item{Title: "Chapter 1 v2", GUID: "a"}, false},
+		{"new ID, same title", models.Feed{LastChapter: text("Chapter 1"), LastItemID: text("a"), Source: withID}, gofeed.Item{Title: "Chapter 1", GUID: "b"}, true},
+		{"item without an ID", models.Feed{LastChapter: text("Chapter 1"), LastItemID: text("a"), Source: withID}, gofeed.Item{Title: "Chapter 1"}, false},
+	} {
+		if got := isNewRelease(tc.feed, &tc.item); got != tc.want {
+			t.Errorf("%s: isNewRelease = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"shinkan-rebirth/internal/models"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// Chapters fetched per check, newest first
+const mangadexLimit = 100
+
+var mangadexID = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+
+// mangadexSource reads a series' chapters from the MangaDex chapter feed
+type mangadexSource struct {
+	get    getFunc
+	api    string
+	config models.MangaDexSource
+}
+
+type mangadexChapter struct {
+	ID         string `json:"id"`
+	Attributes struct {
+		Volume             *string `json:"volume"`
+		Chapter            *string `json:"chapter"`
+		Title              *string `json:"title"`
+		TranslatedLanguage string  `json:"translatedLanguage"`
+		ExternalURL        *string `json:"externalUrl"`
+		PublishAt          string  `json:"publishAt"`
+	} `json:"attributes"`
+	Relationships []struct {
+		ID         string `json:"id"`
+		Type       string `json:"type"`
+		Attributes *struct {
+			Name string `json:"name"`
+		} `json:"attributes"`
+	} `json:"relationships"`
+}
+
+type mangadexChapterList struct {
+	Result string            `json:"result"`
+	Data   []mangadexChapter `json:"data"`
+	Errors []struct {
+		Detail string `json:"detail"`
+	} `json:"errors"`
+}
+
+func validateMangaDex(config *models.MangaDexSource) error {
+	if config == nil {
+		return newFeedError(models.ErrorKindConfig, "MangaDex source needs settings")
+	}
+	if config.MangaID != "" && !mangadexID.MatchString(strings.ToLower(config.MangaID)) {
+		return newFeedError(models.ErrorKindConfig, "invalid MangaDex manga ID %q", config.MangaID)
+	}
+	for _, language := range config.Languages {
+		if strings.TrimSpace(language) == "" {
+			return newFeedError(models.ErrorKindConfig, "empty MangaDex language")
+		}
+	}
+	return nil
+}
+
+// Fetch reads the chapters of the manga in the source settings, or of the
+// MangaDex title linked by url
+func (s *mangadexSource) Fetch(ctx context.Context, feedURL string) (*gofeed.Feed, error) {
+	mangaID := strings.ToLower(s.config.MangaID)
+	if mangaID == "" {
+		mangaID = mangadexID.FindString(strings.ToLower(feedURL))
+	}
+	if mangaID == "" {
+		return nil, newFeedError(models.ErrorKindConfig, "no MangaDex manga ID in settings or URL")
+	}
+
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(mangadexLimit))
+	query.Set("order[chapter]", "desc")
+	query.Set("order[publishAt]", "desc")
+	query.Add("includes[]", "scanlation_group")
+	for _, rating := range []string{"safe", "suggestive", "erotica", "pornographic"} {
+		query.Add("contentRating[]", rating)
+	}
+	for _, language := range s.config.Languages {
+		query.Add("translatedLanguage[]", strings.TrimSpace(language))
+	}
+
+	api := strings.TrimSuffix(s.api, "/")
+	page, err := s.get(ctx, fmt.Sprintf("%s/manga/%s/feed?%s", api, mangaID, query.Encode()),
+		map[string]string{"Accept": "application/json"})
+	if err != nil {
+		return nil, err
+	}
+
+	var list mangadexChapterList
+	if err := json.Unmarshal(page.body, &list); err != nil {
+		return nil, &FeedError{Kind: models.ErrorKindParse, Err: fmt.Errorf("failed to parse MangaDex response: %w", err)}
+	}
+	if list.Result == "error" {
+		detail := "unknown error"
+		if len(list.Errors) > 0 {
+			detail = list.Errors[0].Detail
+		}
+		return nil, newFeedError(models.ErrorKindParse, "MangaDex: %s", detail)
+	}
+
+	feed := &gofeed.Feed{
+		Link:     fmt.Sprintf("https://mangadex.org/title/%s", mangaID),
+		FeedType: string(models.SourceMangaDex),
+	}
+	for _, chapter := range list.Data {
+		group := chapter.group(s.config.Groups)
+		if group == "" && len(s.config.Groups) > 0 {
+			continue
+		}
+		feed.Items = append(feed.Items, chapter.item(group))
+	}
+
+	// Chapter numbers are text in the API, so make sure 10 comes after 9
+	sort.SliceStable(feed.Items, func(i, j int) bool {
+		return chapterNumber(feed.Items[i]) > chapterNumber(feed.Items[j])
+	})
+
+	return feed, nil
+}
+
+// group returns the names of the chapter's scanlation groups, or only the
+// wanted ones if a filter is set, empty if none of them is wanted
+func (ch mangadexChapter) group(wanted []string) string {
+	var names []string
+	for _, rel := range ch.Relationships {
+		if rel.Type != "scanlation_group" {
+			continue
+		}
+		name := rel.ID
+		if rel.Attributes != nil && rel.Attributes.Name != "" {
+			name = rel.Attributes.Name
+		}
+		if len(wanted) == 0 || containsFold(wanted, name) || containsFold(wanted, rel.ID) {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+func (ch mangadexChapter) item(group string) *gofeed.Item {
+	attrs := ch.Attributes
+	info := map[string]string{customLanguage: attrs.TranslatedLanguage}
+	if group != "" {
+		info[customGroup] = group
+	}
+
+	title := "Oneshot"
+	if attrs.Chapter != nil && *attrs.Chapter != "" {
+		title = "Chapter " + *attrs.Chapter
+		info[customChapter] = *attrs.Chapter
+	}
+	if attrs.Volume != nil && *attrs.Volume != "" {
+		info[customVolume] = *attrs.Volume
+	}
+	if attrs.Title != nil && strings.TrimSpace(*attrs.Title) != "" {
+		title += " - " + strings.TrimSpace(*attrs.Title)
+	}
+
+	// Chapters hosted elsewhere, e.g. official releases, link there
+	link := fmt.Sprintf("https://mangadex.org/chapter/%s", ch.ID)
+	if attrs.ExternalURL != nil && *attrs.ExternalURL != "" {
+		link = *attrs.ExternalURL
+	}
+
+	item := &gofeed.Item{
+		Title:     title,
+		Link:      link,
+		GUID:      ch.ID,
+		Published: attrs.PublishAt,
+		Custom:    info,
+	}
+	if date, err := time.Parse(time.RFC3339, attrs.PublishAt); err == nil {
+		item.PublishedParsed = &date
+	}
+	return item
+}
+
+// chapterNumber parses an item's chapter number, -1 for oneshots and
+// chapters without a number
+func chapterNumber(item *gofeed.Item) float64 {
+	n, err := strconv.ParseFloat(item.Custom[customChapter], 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,215 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"shinkan-rebirth/internal/events"
+	"shinkan-rebirth/internal/models"
+	"shinkan-rebirth/internal/storage"
+)
+
+const testMangaID = "a0b1c2d3-e4f5-4a6b-9c8d-7e6f5a4b3c2d"
+
+// recorder is a fake publisher keeping every event
+type recorder struct {
+	mu     sync.Mutex
+	events []events.Event
+}
+
+func (r *recorder) Publish(ctx context.Context, event events.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) releases() []events.ReleaseDetected {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found []events.ReleaseDetected
+	for _, event := range r.events {
+		if release, ok := event.(events.ReleaseDetected); ok {
+			found = append(found, release)
+		}
+	}
+	return found
+}
+
+// fakeMangaDex serves a recorded chapter feed of testMangaID, as the API
+// MANGADEX_API would point to
+type fakeMangaDex struct {
+	*httptest.Server
+	mu      sync.Mutex
+	fixture string
+	query   map[string][]string
+}
+
+func newFakeMangaDex(t *testing.T, fixture string) *fakeMangaDex {
+	m := &fakeMangaDex{fixture: fixture}
+	m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/manga/"+testMangaID+"/feed" {
+			http.NotFound(w, r)
+			return
+		}
+
+		m.mu.Lock()
+		m.query = r.URL.Query()
+		fixture := m.fixture
+		m.mu.Unlock()
+
+		body, err := os.ReadFile(filepath.Join("testdata", "mangadex", fixture))
+		if err != nil {
+			t.Errorf("fixture: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}))
+	t.Cleanup(m.Close)
+	return m
+}
+
+func (m *fakeMangaDex) serve(fixture string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fixture = fixture
+}
+
+func mangadexFeed(config models.MangaDexSource) models.Feed {
+	return models.Feed{
+		ID:     "md",
+		Name:   "Test Manga",
+		RSSUrl: "https://mangadex.org/title/" + testMangaID + "/test-manga",
+		Type:   models.FeedTypeManga,
+		Source: &models.Source{Kind: models.SourceMangaDex, MangaDex: &config},
+	}
+}
+
+func newTestChecker(t *testing.T, api string) (*Checker, *storage.Storage, *recorder) {
+	dir := t.TempDir()
+	store := storage.New(filepath.Join(dir, "mangas.json"), filepath.Join(dir, "anime.json"))
+	publisher := &recorder{}
+	return New(store, nil, publisher, 5*time.Second, api), store, publisher
+}
+
+func TestMangaDexFetch(t *testing.T) {
+	api := newFakeMangaDex(t, "feed.json")
+	c, _, _ := newTestChecker(t, api.URL)
+
+	source, err := c.sourceFor(mangadexFeed(models.MangaDexSource{Languages: []string{"en"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := source.Fetch(context.Background(), "https://mangadex.org/title/"+testMangaID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := api.query["translatedLanguage[]"]; len(got) != 1 || got[0] != "en" {
+		t.Errorf("translatedLanguage[] = %v, want [en]", got)
+	}
+	if got := api.query["includes[]"]; len(got) != 1 || got[0] != "scanlation_group" {
+		t.Errorf("includes[] = %v, want [scanlation_group]", got)
+	}
+
+	// Ordered by chapter number, so 10 comes before 9 and oneshots last
+	want := []struct{ title, link, group, volume string }{
+		{"Chapter 10", "https://publisher.example/series/chapter-10", "Official", "2"},
+		{"Chapter 9 - The Gate", "https://mangadex.org/chapter/6a3b5c1e-0d1f-4c7a-9b2e-1f0c2d3e4a51", "Night Owls", "2"},
+		{"Oneshot - Side Story", "https://mangadex.org/chapter/8c5d7e3a-2f3b-4e9c-9d4a-3b2e4f5a6c73", "Night Owls", ""},
+	}
+	if len(feed.Items) != len(want) {
+		t.Fatalf("got %d items, want %d", len(feed.Items), len(want))
+	}
+	for i, w := range want {
+		item := feed.Items[i]
+		if item.Title != w.title || item.Link != w.link {
+			t.Errorf("item %d = %q %s, want %q %s", i, item.Title, item.Link, w.title, w.link)
+		}
+		info := releaseInfo(item)
+		if info.Group != w.group || info.Volume != w.volume || info.Language != "en" {
+			t.Errorf("item %d info = %+v, want group %q, volume %q, language en", i, info, w.group, w.volume)
+		}
+	}
+}
+
+func TestMangaDexGroupFilter(t *testing.T) {
+	api := newFakeMangaDex(t, "feed.json")
+	c, _, _ := newTestChecker(t, api.URL)
+
+	source, err := c.sourceFor(mangadexFeed(models.MangaDexSource{MangaID: testMangaID, Groups: []string{"night owls"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := source.Fetch(context.Background(), "https://example.com/unused")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(feed.Items) != 2 || feed.Items[0].Title != "Chapter 9 - The Gate" {
+		t.Errorf("items = %d, first %q, want only Night Owls' chapters", len(feed.Items), feed.Items[0].Title)
+	}
+}
+
+func TestMangaDexNewChapterIsDetected(t *testing.T) {
+	api := newFakeMangaDex(t, "feed.json")
+	c, store, publisher := newTestChecker(t, api.URL)
+	ctx := context.Background()
+
+	feed, err := store.AddFeed(mangadexFeed(models.MangaDexSource{Languages: []string{"en"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first check only stores the latest chapter
+	if err := c.CheckFeed(ctx, feed, 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(publisher.releases()); got != 0 {
+		t.Fatalf("first check published %d releases, want 0", got)
+	}
+
+	api.serve("feed-new-chapter.json")
+	feeds, err := store.GetFeeds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CheckFeed(ctx, feeds[0], 1); err != nil {
+		t.Fatal(err)
+	}
+
+	releases := publisher.releases()
+	if len(releases) != 1 {
+		t.Fatalf("published %d releases, want 1", len(releases))
+	}
+	release := releases[0]
+	if release.Title != "Chapter 11 - Homecoming" || release.Link != "https://mangadex.org/chapter/9d6e8f4b-3a4c-4fad-8e5b-4c3f5a6b7d84" {
+		t.Errorf("release = %q %s", release.Title, release.Link)
+	}
+	want := models.ReleaseInfo{Number: "11", Chapter: "11", Volume: "3", Group: "Night Owls", Language: "en"}
+	if release.Info != want {
+		t.Errorf("info = %+v, want %+v", release.Info, want)
+	}
+}
+
+func TestMangaDexUnknownManga(t *testing.T) {
+	api := newFakeMangaDex(t, "feed.json")
+	c, _, _ := newTestChecker(t, api.URL)
+
+	source, err := c.sourceFor(mangadexFeed(models.MangaDexSource{MangaID: "00000000-0000-4000-8000-000000000000"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = source.Fetch(context.Background(), "")
+
+	var feedErr *FeedError
+	if !errors.As(err, &feedErr) || feedErr.Kind != models.ErrorKindHTTP4xx || feedErr.StatusCode != http.StatusNotFound {
+		t.Errorf("err = %v, want a 404 feed error", err)
+	}
+}
@@ -0,0 +1,132 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"shinkan-rebirth/internal/models"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// Words in item titles that hint at the feed type
+var (
+	animeHints = []string{"episode", "1080p", "720p", "480p", ".mkv", "x264", "x265", "hevc"}
+	mangaHints = []string{"chapter", "ch.", "vol."}
+)
+
+// PreviewFeed fetches a feed that isn't stored yet and reports what it
+// contains, with the latest limit items and warnings about anything that
+// would make it a poor fit for its type and search text
+func (c *Checker) PreviewFeed(ctx context.Context, feed models.Feed, limit int) models.FeedPreview {
+	preview := models.FeedPreview{
+		URL:      feed.RSSUrl,
+		Items:    []models.PreviewItem{},
+		Warnings: []string{},
+	}
+
+	rssFeed, err := c.fetchFeed(ctx, feed)
+	if err != nil {
+		feedErr := classifyError(err)
+		preview.Error = feedErr.Error()
+		preview.ErrorKind = feedErr.Kind
+		return preview
+	}
+
+	feedType := feed.Type
+	searchText := ""
+	if feed.SearchText != nil {
+		searchText = *feed.SearchText
+	}
+
+	preview.Title = rssFeed.Title
+	preview.ItemCount = len(rssFeed.Items)
+	preview.DetectedType = detectType(rssFeed)
+
+	missingLinks, missingDates := 0, 0
+	for i, item := range rssFeed.Items {
+		matches := matchesSearch(feed, item)
+		if matches {
+			preview.SearchMatches++
+		}
+		if item.Link == "" {
+			missingLinks++
+		}
+		if item.PublishedParsed == nil {
+			missingDates++
+		}
+
+		if i < limit {
+			previewItem := models.PreviewItem{Title: item.Title, Link: item.Link, Matches: matches, ReleaseInfo: releaseInfo(item)}
+			if item.PublishedParsed != nil {
+				previewItem.Date = item.PublishedParsed.Format(time.RFC3339)
+			}
+			preview.Items = append(preview.Items, previewItem)
+		}
+	}
+
+	warn := func(format string, args ...interface{}) {
+		preview.Warnings = append(preview.Warnings, fmt.Sprintf(format, args...))
+	}
+
+	if preview.DetectedType != "" && preview.DetectedType != feedType {
+		warn("Items look like %s releases, but the feed would be added as %s", preview.DetectedType, feedType)
+	}
+	if searchText != "" && feedType != models.FeedTypeAnime {
+		warn("Search text only applies to anime feeds and will be ignored")
+	} else if searchText != "" && preview.SearchMatches == 0 {
+		warn("Search text %q matches none of the %d items", searchText, len(rssFeed.Items))
+	} else if searchText == "" && feedType == models.FeedTypeAnime && len(rssFeed.Items) > 1 {
+		warn("Without search text every new item in the feed is notified")
+	}
+	if missingLinks > 0 {
+		warn("%d item(s) have no link", missingLinks)
+	}
+	if missingDates == len(rssFeed.Items) {
+		warn("Items have no publish dates")
+	}
+	if feed.SourceKind() == models.SourceHTML && len(rssFeed.Items) == 1 {
+		warn("The item selector matches a single element, it should match every release")
+	}
+
+	return preview
+}
+
+// detectType guesses whether a feed carries anime or manga releases from
+// torrent metadata and common words in item titles, returning an empty type
+// if it can't tell
+func detectType(rssFeed *gofeed.Feed) models.FeedType {
+	anime, manga := 0, 0
+	for _, item := range rssFeed.Items {
+		if _, ok := item.Extensions["nyaa"]; ok {
+			anime++
+		}
+		for _, enclosure := range item.Enclosures {
+			if enclosure.Type == "application/x-bittorrent" {
+				anime++
+			}
+		}
+
+		title := strings.ToLower(item.Title)
+		for _, hint := range animeHints {
+			if strings.Contains(title, hint) {
+				anime++
+			}
+		}
+		for _, hint := range mangaHints {
+			if strings.Contains(title, hint) {
+				manga++
+			}
+		}
+	}
+
+	switch {
+	case anime > manga:
+		return models.FeedTypeAnime
+	case manga > anime:
+		return models.FeedTypeManga
+	}
+	return ""
+}
@@ -0,0 +1,322 @@
+package checker
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"shinkan-rebirth/internal/models"
+
+	"github.com/mmcdole/gofeed"
+)
+
+var (
+	releaseGroup = regexp.MustCompile(`^\s*\[([^\]]+)\]`)
+
+	// "S01E05", "- 05", "- 05v2" and "Episode 5", tried in this order
+	episodePatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\bS\d{1,2}E(\d{1,4}(?:\.\d)?)`),
+		regexp.MustCompile(`\s-\s(\d{1,4}(?:\.\d)?)(?:v\d)?(?:\s|$|\[|\()`),
+		regexp.MustCompile(`(?i)\b(?:episode|ep)\.?\s*(\d{1,4}(?:\.\d)?)\b`),
+	}
+
+	// "S02E05", "S2 - 05", "Season 2" and "2nd Season", tried in this order
+	seasonPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\bS(\d{1,2})E\d`),
+		regexp.MustCompile(`(?i)\bS(\d{1,2})\b`),
+		regexp.MustCompile(`(?i)\bseason\s*(\d{1,2})\b`),
+		regexp.MustCompile(`(?i)\b(\d{1,2})(?:st|nd|rd|th)\s+season\b`),
+	}
+
+	// Episode numbers as stored, "5" or "S2E5"
+	episodeKeyPattern = regexp.MustCompile(`^S(\d+)E(.+)$`)
+
+	// "Chapter 12", "Ch. 12.5" and "Vol. 3 Ch 40"
+	chapterPattern = regexp.MustCompile(`(?i)\b(?:chapter|chap|ch)\.?\s*(\d{1,5}(?:\.\d+)?)`)
+
+	resolutionPattern = regexp.MustCompile(`(?i)\b(480|540|576|720|1080|1440|2160)p\b|\b(4k)\b`)
+)
+
+// Spellings of each codec in release titles
+var codecAliases = map[string][]string{
+	"hevc": {"hevc", "x265", "h265", "h.265"},
+	"avc":  {"avc", "x264", "h264", "h.264"},
+	"av1":  {"av1"},
+}
+
+// parsedRelease is what an anime release title says about the upload
+type parsedRelease struct {
+	group      string
+	episode    string // Without leading zeros and with the season after the first, e.g. "5", "12.5" or "S2E1"
+	resolution string // e.g. "1080p"
+	codec      string // "hevc", "avc" or "av1"
+}
+
+// episodeDecision is what a check does with a feed that has release
+// preferences
+type episodeDecision struct {
+	action  models.CheckAction
+	item    *gofeed.Item // Best release of the newest episode
+	episode string
+	pending *models.PendingEpisode // Stored on the feed, nil clears it
+}
+
+// ValidatePreferences checks a feed's release preferences
+func ValidatePreferences(prefs *models.ReleasePreferences) error {
+	if prefs == nil {
+		return nil
+	}
+	if prefs.GracePeriod != "" {
+		if grace, err := time.ParseDuration(prefs.GracePeriod); err != nil || grace < 0 {
+			return newFeedError(models.ErrorKindConfig, "invalid grace period %q, use a duration like 2h", prefs.GracePeriod)
+		}
+	}
+	if prefs.Resolution != "" && normalizeResolution(prefs.Resolution) == "" {
+		return newFeedError(models.ErrorKindConfig, "unknown resolution %q", prefs.Resolution)
+	}
+	if prefs.Codec != "" && normalizeCodec(prefs.Codec) == "" {
+		return newFeedError(models.ErrorKindConfig, "unknown codec %q", prefs.Codec)
+	}
+	return nil
+}
+
+func parseRelease(title string) parsedRelease {
+	var release parsedRelease
+	if m := releaseGroup.FindStringSubmatch(title); m != nil {
+		release.group = strings.TrimSpace(m[1])
+	}
+	for _, pattern := range episodePatterns {
+		if m := pattern.FindStringSubmatch(title); m != nil {
+			release.episode = episodeKey(parseSeason(title), normalizeEpisode(m[1]))
+			break
+		}
+	}
+	if m := resolutionPattern.FindString(title); m != "" {
+		release.resolution = normalizeResolution(m)
+	}
+	release.codec = normalizeCodec(title)
+	return release
+}
+
+// parseSeason reads the season of a release title, 1 if it names none
+func parseSeason(title string) int {
+	for _, pattern := range seasonPatterns {
+		if m := pattern.FindStringSubmatch(title); m != nil {
+			if season, err := strconv.Atoi(m[1]); err == nil && season > 0 {
+				return season
+			}
+		}
+	}
+	return 1
+}
+
+// episodeKey is an episode number with its season, left out for the first
+// so feeds stored before seasons keep comparing equal
+func episodeKey(season int, number string) string {
+	if season > 1 {
+		return fmt.Sprintf("S%dE%s", season, number)
+	}
+	return number
+}
+
+// splitEpisode reads the season and number back from a chapter or
+// episode number
+func splitEpisode(number string) (int, float64) {
+	season := 1
+	if m := episodeKeyPattern.FindStringSubmatch(number); m != nil {
+		season, _ = strconv.Atoi(m[1])
+		number = m[2]
+	}
+	n, _ := strconv.ParseFloat(number, 64)
+	return season, n
+}
+
+// releaseNumber is the chapter or episode number of an item, normalized so
+// the same release from different feeds compares equal, e.g. "Chapter 012"
+// and "Ch. 12" both give "12". Empty if the title has no number.
+func releaseNumber(item *gofeed.Item) string {
+	if chapter := item.Custom[customChapter]; chapter != "" {
+		return normalizeEpisode(chapter)
+	}
+	return ReleaseNumber(item.Title)
+}
+
+// ReleaseNumber reads the chapter or episode number from a release title,
+// empty if it has none
+func ReleaseNumber(title string) string {
+	if m := chapterPattern.FindStringSubmatch(title); m != nil {
+		return normalizeEpisode(m[1])
+	}
+	return parseRelease(title).episode
+}
+
+// CompareNumbers orders two chapter or episode numbers, -1 if a comes
+// before b. A later season comes after every episode of the earlier ones,
+// and numbers that don't parse sort first.
+func CompareNumbers(a, b string) int {
+	seasonA, x := splitEpisode(a)
+	seasonB, y := splitEpisode(b)
+	switch {
+	case seasonA != seasonB:
+		if seasonA < seasonB {
+			return -1
+		}
+		return 1
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func normalizeEpisode(raw string) string {
+	n, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return raw
+	}
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// normalizeResolution turns "1080", "1080P" or "4K" into "1080p" or
+// "2160p", empty if it isn't a resolution
+func normalizeResolution(raw string) string {
+	raw = strings.ToLower(strings.TrimSpace(raw))
+	if raw == "4k" {
+		return "2160p"
+	}
+	raw = strings.TrimSuffix(raw, "p")
+	if _, err := strconv.Atoi(raw); err != nil {
+		return ""
+	}
+	return raw + "p"
+}
+
+// normalizeCodec finds the first codec spelled in text, empty if none
+func normalizeCodec(text string) string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '.')
+	})
+	for _, field := range fields {
+		field = strings.Trim(field, ".")
+		for codec, aliases := range codecAliases {
+			for _, alias := range aliases {
+				if field == alias {
+					return codec
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// score ranks a release by the preferences. Each group step outweighs a
+// resolution and codec match together.
+func score(prefs models.ReleasePreferences, release parsedRelease) int {
+	total := 0
+	for i, group := range prefs.Groups {
+		if strings.EqualFold(strings.TrimSpace(group), release.group) {
+			total += (len(prefs.Groups) - i) * 4
+			break
+		}
+	}
+	if prefs.Resolution != "" && normalizeResolution(prefs.Resolution) == release.resolution {
+		total += 2
+	}
+	if prefs.Codec != "" && normalizeCodec(prefs.Codec) == release.codec {
+		total++
+	}
+	return total
+}
+
+// bestScore is the score of a release matching every preference
+func bestScore(prefs models.ReleasePreferences) int {
+	total := len(prefs.Groups) * 4
+	if prefs.Resolution != "" {
+		total += 2
+	}
+	if prefs.Codec != "" {
+		total++
+	}
+	return total
+}
+
+// decideEpisode picks the best release of the newest episode in a feed.
+// A new episode is held until a release matching every preference appears
+// or the grace period ends, and is notified once however many groups upload
+// it. ok is false when no item has an episode number, so the feed is
+// tracked by title as usual.
+func decideEpisode(feed models.Feed, rssFeed *gofeed.Feed, now time.Time) (decision episodeDecision, ok bool) {
+	prefs := *feed.Preferences
+
+	var newest string
+	releases := make(map[*gofeed.Item]parsedRelease)
+	for _, item := range rssFeed.Items {
+		if !matchesSearch(feed, item) {
+			continue
+		}
+		release := parseRelease(item.Title)
+		if release.episode == "" {
+			continue
+		}
+		releases[item] = release
+		if newest == "" || CompareNumbers(release.episode, newest) > 0 {
+			newest = release.episode
+		}
+	}
+	if newest == "" {
+		return decision, false
+	}
+
+	// Feed order breaks ties, so the newest upload of equal releases wins
+	bestScoreSeen := -1
+	for _, item := range rssFeed.Items {
+		release, ok := releases[item]
+		if !ok || release.episode != newest {
+			continue
+		}
+		if s := score(prefs, release); s > bestScoreSeen {
+			bestScoreSeen = s
+			decision.item = item
+		}
+	}
+	decision.episode = newest
+
+	// Feeds tracked by title before preferences were set continue from the
+	// episode of their last chapter
+	last := ""
+	if feed.LastEpisode != nil {
+		last = *feed.LastEpisode
+	} else if feed.LastChapter != nil {
+		last = parseRelease(*feed.LastChapter).episode
+	}
+
+	switch {
+	case feed.LastChapter == nil && feed.LastEpisode == nil:
+		decision.action = models.CheckActionFirstCheck
+		return decision, true
+	case last != "" && CompareNumbers(newest, last) <= 0:
+		decision.action = models.CheckActionUnchanged
+		decision.episode = last
+		return decision, true
+	}
+
+	since := now
+	if feed.Pending != nil && feed.Pending.Episode == newest {
+		if t, err := time.Parse(time.RFC3339, feed.Pending.Since); err == nil {
+			since = t
+		}
+	}
+	grace, _ := time.ParseDuration(prefs.GracePeriod)
+
+	if bestScoreSeen >= bestScore(prefs) || now.Sub(since) >= grace {
+		decision.action = models.CheckActionNew
+		return decision, true
+	}
+
+	decision.action = models.CheckActionWaiting
+	decision.pending = &models.PendingEpisode{Episode: newest, Since: since.Format(time.RFC3339)}
+	return decision, true
+}
@@ -0,0 +1,117 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"shinkan-rebirth/internal/models"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// Pages and feeds are read up to this size
+const maxPageSize = 10 << 20
+
+// Source reads the releases behind a feed URL. Every source returns them as
+// a gofeed.Feed with the newest item first, so feeds are checked the same
+// way whatever they are read from.
+type Source interface {
+	Fetch(ctx context.Context, url string) (*gofeed.Feed, error)
+}
+
+// page is a downloaded document and the URL it ended up at
+type page struct {
+	body []byte
+	url  *url.URL
+}
+
+// getFunc downloads a page, sending the given extra headers
+type getFunc func(ctx context.Context, url string, headers map[string]string) (*page, error)
+
+// sourceFor returns the source a feed is read from
+func (c *Checker) sourceFor(feed models.Feed) (Source, error) {
+	if err := ValidateSource(feed.Source); err != nil {
+		return nil, err
+	}
+
+	switch feed.SourceKind() {
+	case models.SourceHTML:
+		return &htmlSource{get: c.get, config: *feed.Source.HTML}, nil
+	case models.SourceJSON:
+		return &jsonSource{get: c.get, config: *feed.Source.JSON}, nil
+	case models.SourceMangaDex:
+		return &mangadexSource{get: c.get, api: c.mangadexAPI, config: *feed.Source.MangaDex}, nil
+	default:
+		return &rssSource{get: c.get, parser: c.parser}, nil
+	}
+}
+
+// ValidateSource checks a feed's source settings. A nil source is RSS.
+func ValidateSource(source *models.Source) error {
+	if source == nil {
+		return nil
+	}
+
+	switch source.Kind {
+	case "", models.SourceRSS:
+		return nil
+	case models.SourceHTML:
+		return validateHTML(source.HTML)
+	case models.SourceJSON:
+		return validateJSON(source.JSON)
+	case models.SourceMangaDex:
+		return validateMangaDex(source.MangaDex)
+	}
+	return newFeedError(models.ErrorKindConfig, "unknown source kind %q", source.Kind)
+}
+
+// sourceKey identifies what is fetched for a feed, feeds with the same key
+// share a single request
+func sourceKey(feed models.Feed) string {
+	if feed.SourceKind() == models.SourceRSS {
+		return feed.RSSUrl
+	}
+	config, _ := json.Marshal(feed.Source)
+	return feed.RSSUrl + "\x00" + string(config)
+}
+
+type rssSource struct {
+	get    getFunc
+	parser *gofeed.Parser
+}
+
+func (s *rssSource) Fetch(ctx context.Context, url string) (*gofeed.Feed, error) {
+	page, err := s.get(ctx, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rssFeed, err := s.parser.Parse(bytes.NewReader(page.body))
+	if err != nil {
+		return nil, &FeedError{Kind: models.ErrorKindParse, Err: fmt.Errorf("failed to parse RSS: %w", err)}
+	}
+	return rssFeed, nil
+}
+
+// Keys of release details in gofeed.Item.Custom
+const (
+	customChapter  = "chapter"
+	customVolume   = "volume"
+	customGroup    = "group"
+	customLanguage = "language"
+)
+
+// releaseInfo returns the release details a source stored on an item
+func releaseInfo(item *gofeed.Item) models.ReleaseInfo {
+	return models.ReleaseInfo{
+		Number:   releaseNumber(item),
+		Chapter:  item.Custom[customChapter],
+		Volume:   item.Custom[customVolume],
+		Group:    item.Custom[customGroup],
+		Language: item.Custom[customLanguage],
+		Torrent:  torrentInfo(item),
+	}
+}
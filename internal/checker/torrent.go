@@ -0,0 +1,108 @@
+package checker
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"shinkan-rebirth/internal/models"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// Trackers added to magnet links built from an info hash, those Nyaa uses
+var magnetTrackers = []string{
+	"http://nyaa.tracker.wf:7777/announce",
+	"udp://open.stealth.si:80/announce",
+	"udp://tracker.opentrackr.org:1337/announce",
+	"udp://exodus.desync.com:6969/announce",
+	"udp://tracker.torrent.eu.org:451/announce",
+}
+
+var magnetHash = regexp.MustCompile(`(?i)xt=urn:btih:([0-9a-z]+)`)
+
+// torrentInfo reads the torrent metadata of an item from the Nyaa or ezRSS
+// torrent extensions, torrent enclosures or magnet links, nil if the item
+// isn't a torrent
+func torrentInfo(item *gofeed.Item) *models.TorrentInfo {
+	info := &models.TorrentInfo{
+		InfoHash: extension(item, "infoHash"),
+		Magnet:   extension(item, "magnetURI"),
+		Size:     extension(item, "size"),
+		Category: extension(item, "category"),
+	}
+
+	info.Seeders, _ = strconv.Atoi(extension(item, "seeders", "seeds"))
+	if info.Size == "" {
+		info.Size = formatSize(extension(item, "contentLength"))
+	}
+
+	for _, enclosure := range item.Enclosures {
+		if enclosure.Type == "application/x-bittorrent" {
+			info.URL = enclosure.URL
+			if info.Size == "" {
+				info.Size = formatSize(enclosure.Length)
+			}
+		}
+	}
+	switch {
+	case strings.HasPrefix(item.Link, "magnet:"):
+		info.Magnet = item.Link
+	case info.URL == "" && strings.HasSuffix(strings.ToLower(item.Link), ".torrent"):
+		info.URL = item.Link
+	}
+
+	if info.InfoHash == "" {
+		if m := magnetHash.FindStringSubmatch(info.Magnet); m != nil {
+			info.InfoHash = m[1]
+		}
+	}
+	if info.InfoHash == "" && info.URL == "" && info.Magnet == "" {
+		return nil
+	}
+
+	info.InfoHash = strings.ToLower(info.InfoHash)
+	if info.Magnet == "" && info.InfoHash != "" {
+		info.Magnet = magnetLink(info.InfoHash, item.Title)
+	}
+	return info
+}
+
+// extension returns the first value of any of the named elements in the
+// nyaa or torrent namespace
+func extension(item *gofeed.Item, names ...string) string {
+	for _, namespace := range []string{"nyaa", "torrent"} {
+		for _, name := range names {
+			if values := item.Extensions[namespace][name]; len(values) > 0 {
+				return strings.TrimSpace(values[0].Value)
+			}
+		}
+	}
+	return ""
+}
+
+func magnetLink(infoHash, title string) string {
+	link := "magnet:?xt=urn:btih:" + infoHash + "&dn=" + url.QueryEscape(title)
+	for _, tracker := range magnetTrackers {
+		link += "&tr=" + url.QueryEscape(tracker)
+	}
+	return link
+}
+
+// formatSize turns a size in bytes into text like "1.4 GiB", keeping text
+// that isn't a number
+func formatSize(raw string) string {
+	bytes, err := strconv.ParseFloat(raw, 64)
+	if err != nil || bytes <= 0 {
+		return raw
+	}
+
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	unit := 0
+	for bytes >= 1024 && unit < len(units)-1 {
+		bytes /= 1024
+		unit++
+	}
+	return strconv.FormatFloat(bytes, 'f', 1, 64) + " " + units[unit]
+}
@@ -1,54 +1,124 @@
 package config
 
 import (
+	"errors"
 	"log"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	GotifyServer     string
-	GotifyToken      string
-	DiscordToken     string
-	DiscordChannelID string
-	WebPort          string
-	CheckInterval    string
-	MangaDataFile    string
-	AnimeDataFile    string
+	GotifyServer         string
+	GotifyToken          string
+	DiscordToken         string
+	DiscordChannelID     string
+	WebPort              string
+	CheckInterval        string
+	MangaDataFile        string
+	AnimeDataFile        string
+	StatsFile            string
+	StatsInterval        time.Duration // How often stats are saved
+	ImportAnimeRSS       string        // RSS URL template for imported anime, %s is the title
+	FetchTimeout         time.Duration
+	NotifyTimeout        time.Duration
+	ShutdownTimeout      time.Duration
+	AdminPassword        string // bcrypt hash, enables authentication when set
+	APIKeysFile          string
+	UsersFile            string
+	SessionTTL           time.Duration
+	CORSOrigins          string
+	DedupeWindow         time.Duration // How long a series release is remembered to skip it from other feeds
+	DedupeFile           string        // Series releases notified within the dedupe window
+	MangaDexAPI          string        // Base URL of the MangaDex API, for MangaDex sources
+	QBittorrentURL       string        // qBittorrent WebUI, enables hand-off to qBittorrent
+	QBittorrentUsername  string
+	QBittorrentPassword  string
+	TransmissionURL      string // Transmission RPC endpoint, enables hand-off to Transmission
+	TransmissionUsername string
+	TransmissionPassword string
+	QuietHours           string        // e.g. 23:00-07:00, notifications are held and sent as a summary afterwards
+	QuietHoursGotify     string        // Overrides QuietHours for Gotify, "off" for none
+	QuietHoursDiscord    string        // Overrides QuietHours for Discord, "off" for none
+	QuietHoursTimezone   string        // IANA time zone of the quiet hours, local time when empty
+	NotifyBatchWindow    time.Duration // Releases within this long are sent as one message, 0 for none
+	NotifyQueueFile      string        // Held notifications
 }
 
+// Load reads the configuration and exits if it isn't usable
 func Load() *Config {
+	cfg := Read()
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("❌ ERROR: %v", err)
+	}
+	return cfg
+}
+
+// Read reads the configuration from the environment and .env file without
+// validating it, for commands that don't send notifications
+func Read() *Config {
 	// Load .env file if it exists
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using environment variables")
 	}
 
 	cfg := &Config{
-		GotifyServer:     getEnv("GOTIFY_SERVER", ""),
-		GotifyToken:      getEnv("GOTIFY_TOKEN", ""),
-		DiscordToken:     getEnv("DISCORD_TOKEN", ""),
-		DiscordChannelID: getEnv("DISCORD_CHANNEL_ID", ""),
-		WebPort:          getEnv("WEB_PORT", "11111"),
-		CheckInterval:    getEnv("CHECK_INTERVAL", "0 * * * *"),
-		MangaDataFile:    getEnv("MANGA_DATA_FILE", "./data/mangas.json"),
-		AnimeDataFile:    getEnv("ANIME_DATA_FILE", "./data/anime.json"),
+		GotifyServer:         getEnv("GOTIFY_SERVER", ""),
+		GotifyToken:          getEnv("GOTIFY_TOKEN", ""),
+		DiscordToken:         getEnv("DISCORD_TOKEN", ""),
+		DiscordChannelID:     getEnv("DISCORD_CHANNEL_ID", ""),
+		WebPort:              getEnv("WEB_PORT", "11111"),
+		CheckInterval:        getEnv("CHECK_INTERVAL", "0 * * * *"),
+		MangaDataFile:        getEnv("MANGA_DATA_FILE", "./data/mangas.json"),
+		AnimeDataFile:        getEnv("ANIME_DATA_FILE", "./data/anime.json"),
+		StatsFile:            getEnv("STATS_FILE", "./data/stats.json"),
+		StatsInterval:        getDurationEnv("STATS_SAVE_INTERVAL", 5*time.Minute),
+		ImportAnimeRSS:       getEnv("IMPORT_ANIME_RSS", "https://nyaa.si/?page=rss&q=%s&c=1_2&f=0"),
+		FetchTimeout:         getDurationEnv("FETCH_TIMEOUT", 30*time.Second),
+		NotifyTimeout:        getDurationEnv("NOTIFY_TIMEOUT", 10*time.Second),
+		ShutdownTimeout:      getDurationEnv("SHUTDOWN_TIMEOUT", 30*time.Second),
+		AdminPassword:        getEnv("ADMIN_PASSWORD_HASH", ""),
+		APIKeysFile:          getEnv("API_KEYS_FILE", "./data/apikeys.json"),
+		UsersFile:            getEnv("USERS_FILE", "./data/users.json"),
+		SessionTTL:           getDurationEnv("SESSION_TTL", 7*24*time.Hour),
+		CORSOrigins:          getEnv("CORS_ORIGINS", "*"),
+		DedupeWindow:         getOptionalDurationEnv("DEDUPE_WINDOW", 48*time.Hour),
+		DedupeFile:           getEnv("DEDUPE_FILE", "./data/dedupe.json"),
+		MangaDexAPI:          getEnv("MANGADEX_API", "https://api.mangadex.org"),
+		QBittorrentURL:       getEnv("QBITTORRENT_URL", ""),
+		QBittorrentUsername:  getEnv("QBITTORRENT_USERNAME", ""),
+		QBittorrentPassword:  getEnv("QBITTORRENT_PASSWORD", ""),
+		TransmissionURL:      getEnv("TRANSMISSION_URL", ""),
+		TransmissionUsername: getEnv("TRANSMISSION_USERNAME", ""),
+		TransmissionPassword: getEnv("TRANSMISSION_PASSWORD", ""),
+		QuietHours:           getEnv("QUIET_HOURS", ""),
+		QuietHoursGotify:     getEnv("QUIET_HOURS_GOTIFY", ""),
+		QuietHoursDiscord:    getEnv("QUIET_HOURS_DISCORD", ""),
+		QuietHoursTimezone:   getEnv("QUIET_HOURS_TZ", ""),
+		NotifyBatchWindow:    getOptionalDurationEnv("NOTIFY_BATCH_WINDOW", 0),
+		NotifyQueueFile:      getEnv("NOTIFY_QUEUE_FILE", "./data/notify-queue.json"),
 	}
 
-	// Validate required configuration (at least one notification method)
+	return cfg
+}
+
+// Validate checks the required configuration
+func (cfg *Config) Validate() error {
+	// At least one notification method
 	if cfg.GotifyServer == "" && cfg.DiscordToken == "" {
-		log.Fatal("❌ ERROR: Either GOTIFY_SERVER or DISCORD_TOKEN must be set in .env file")
+		return errors.New("either GOTIFY_SERVER or DISCORD_TOKEN must be set in .env file")
 	}
 
 	if cfg.GotifyServer != "" && cfg.GotifyToken == "" {
-		log.Fatal("❌ ERROR: GOTIFY_TOKEN is required when GOTIFY_SERVER is set")
+		return errors.New("GOTIFY_TOKEN is required when GOTIFY_SERVER is set")
 	}
 
 	if cfg.DiscordToken != "" && cfg.DiscordChannelID == "" {
-		log.Fatal("❌ ERROR: DISCORD_CHANNEL_ID is required when DISCORD_TOKEN is set")
+		return errors.New("DISCORD_CHANNEL_ID is required when DISCORD_TOKEN is set")
 	}
 
-	return cfg
+	return nil
 }
 
 func getEnv(key, defaultValue string) string {
@@ -57,3 +127,30 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Fatalf("❌ ERROR: %s must be a positive duration like 30s or 1m, got %q", key, value)
+	}
+	return duration
+}
+
+// getOptionalDurationEnv is getDurationEnv for settings that 0 turns off
+func getOptionalDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		log.Fatalf("❌ ERROR: %s must be a duration like 30s or 1m, or 0 to turn it off, got %q", key, value)
+	}
+	return duration
+}
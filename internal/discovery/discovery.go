@@ -0,0 +1,177 @@
+// Package discovery finds the RSS or Atom feed for a page URL, so users can
+// paste the series page they have open instead of hunting for the feed.
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"shinkan-rebirth/internal/models"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// Where a candidate was found
+const (
+	SourceDirect  = "direct"  // The URL is a feed itself
+	SourceLink    = "link"    // <link rel="alternate"> in the page
+	SourceAnchor  = "anchor"  // A link in the page that looks like a feed
+	SourcePattern = "pattern" // Known URL scheme of the site
+	SourceGuess   = "guess"   // The page URL with /rss appended
+)
+
+// How many candidates are fetched to verify them
+const maxCandidates = 5
+
+// Pages and feeds are read up to this size
+const maxBodySize = 5 << 20
+
+// ErrNoFeed is returned when a page could be fetched but offers no feed
+var ErrNoFeed = errors.New("no RSS or Atom feed found at this URL")
+
+type Discoverer struct {
+	httpClient *http.Client
+	parser     *gofeed.Parser
+}
+
+// New creates a discoverer. timeout bounds each request.
+func New(timeout time.Duration) *Discoverer {
+	return &Discoverer{
+		httpClient: &http.Client{Timeout: timeout},
+		parser:     gofeed.NewParser(),
+	}
+}
+
+// Resolve returns the feed URL to store for what a user entered: the URL
+// itself if it is a feed, otherwise the first feed discovered for the page,
+// along with all candidates. ErrNoFeed means the page offers no feed; if the
+// page can't be fetched at all the URL is returned unchanged with the error.
+func (d *Discoverer) Resolve(ctx context.Context, pageURL string) (string, []models.DiscoveredFeed, error) {
+	candidates, err := d.Discover(ctx, pageURL)
+	if err != nil {
+		return pageURL, nil, err
+	}
+	return candidates[0].URL, candidates, nil
+}
+
+// Discover lists the feeds found for a page, each verified by fetching it.
+// A URL that is a feed itself is returned as the only candidate.
+func (d *Discoverer) Discover(ctx context.Context, pageURL string) ([]models.DiscoveredFeed, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("invalid URL: %s", pageURL)
+	}
+
+	body, finalURL, err := d.get(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if feed, ok := d.parse(body); ok {
+		return []models.DiscoveredFeed{discovered(pageURL, SourceDirect, feed)}, nil
+	}
+
+	// Links in the page come first, site patterns catch pages that don't
+	// advertise their feed
+	type candidate struct{ url, source string }
+	var candidates []candidate
+	seen := map[string]bool{pageURL: true}
+	add := func(u, source string) {
+		if !seen[u] {
+			seen[u] = true
+			candidates = append(candidates, candidate{u, source})
+		}
+	}
+
+	links, anchors := findLinks(body, finalURL)
+	for _, u := range links {
+		add(u, SourceLink)
+	}
+	for _, u := range sitePatterns(base) {
+		add(u, SourcePattern)
+	}
+	for _, u := range anchors {
+		add(u, SourceAnchor)
+	}
+	if len(candidates) == 0 {
+		add(strings.TrimSuffix(pageURL, "/")+"/rss", SourceGuess)
+	}
+
+	if len(candidates) > maxCandidates {
+		candidates = candidates[:maxCandidates]
+	}
+
+	found := make([]models.DiscoveredFeed, 0, len(candidates))
+	for _, c := range candidates {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		body, _, err := d.get(ctx, c.url)
+		if err != nil {
+			continue
+		}
+		if feed, ok := d.parse(body); ok {
+			found = append(found, discovered(c.url, c.source, feed))
+		}
+	}
+
+	if len(found) == 0 {
+		return nil, ErrNoFeed
+	}
+	return found, nil
+}
+
+// get fetches a URL, returning the body and the URL after redirects
+func (d *Discoverer) get(ctx context.Context, rawURL string) ([]byte, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("User-Agent", d.parser.UserAgent)
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, nil, fmt.Errorf("%s returned status %d", rawURL, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, resp.Request.URL, nil
+}
+
+// parse reports whether body is a feed, parsing it if so
+func (d *Discoverer) parse(body []byte) (*gofeed.Feed, bool) {
+	if gofeed.DetectFeedType(bytes.NewReader(body)) == gofeed.FeedTypeUnknown {
+		return nil, false
+	}
+	feed, err := d.parser.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, false
+	}
+	return feed, true
+}
+
+func discovered(u, source string, feed *gofeed.Feed) models.DiscoveredFeed {
+	return models.DiscoveredFeed{
+		URL:       u,
+		Title:     feed.Title,
+		Format:    feed.FeedType,
+		Source:    source,
+		ItemCount: len(feed.Items),
+	}
+}
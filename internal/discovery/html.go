@@ -0,0 +1,88 @@
+package discovery
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Link types announcing a feed
+var feedTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+	"application/xml":       true,
+	"text/xml":              true,
+}
+
+// Anchor targets that usually are feeds
+var feedSuffixes = []string{"/rss", "/feed", ".rss", ".atom", "rss.xml", "atom.xml", "feed.xml", "page=rss"}
+
+// findLinks returns the feeds announced with <link rel="alternate"> and
+// the links in the page that look like feeds, resolved against base
+func findLinks(body []byte, base *url.URL) (links, anchors []string) {
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return links, anchors
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			attrs := make(map[string]string, len(token.Attr))
+			for _, attr := range token.Attr {
+				attrs[attr.Key] = attr.Val
+			}
+
+			href := resolve(base, attrs["href"])
+			if href == "" {
+				continue
+			}
+
+			switch token.Data {
+			case "link":
+				rel := strings.Fields(strings.ToLower(attrs["rel"]))
+				if contains(rel, "alternate") && feedTypes[strings.ToLower(attrs["type"])] {
+					links = append(links, href)
+				}
+			case "a":
+				lower := strings.ToLower(strings.TrimSuffix(href, "/"))
+				for _, suffix := range feedSuffixes {
+					if strings.HasSuffix(lower, suffix) {
+						anchors = append(anchors, href)
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+func resolve(base *url.URL, href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") {
+		return ""
+	}
+
+	ref, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	resolved := base.ResolveReference(ref)
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return ""
+	}
+	resolved.Fragment = ""
+	return resolved.String()
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package discovery
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	mangadexTitle     = regexp.MustCompile(`^/title/([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})`)
+	mangaupdatesTitle = regexp.MustCompile(`^/series/([0-9a-z]+)`)
+	nyaaUser          = regexp.MustCompile(`^/user/([^/]+)`)
+)
+
+// sitePatterns returns the feed URLs of sites whose feeds follow a known
+// scheme but aren't linked from their pages
+func sitePatterns(page *url.URL) []string {
+	host := strings.TrimPrefix(strings.ToLower(page.Hostname()), "www.")
+
+	switch host {
+	case "mangadex.org":
+		if m := mangadexTitle.FindStringSubmatch(page.Path); m != nil {
+			return []string{fmt.Sprintf("https://mangadex.org/title/%s/rss", m[1])}
+		}
+
+	case "mangaupdates.com":
+		// Series pages use a base 36 ID, the API the decimal one
+		if m := mangaupdatesTitle.FindStringSubmatch(page.Path); m != nil {
+			if id, err := strconv.ParseInt(m[1], 36, 64); err == nil {
+				return []string{fmt.Sprintf("https://api.mangaupdates.com/v1/series/%d/rss", id)}
+			}
+		}
+
+	case "nyaa.si", "sukebei.nyaa.si":
+		if m := nyaaUser.FindStringSubmatch(page.Path); m != nil {
+			return []string{fmt.Sprintf("https://%s/?page=rss&u=%s", page.Host, url.QueryEscape(m[1]))}
+		}
+		// Search results become the same search as RSS
+		if page.Path == "" || page.Path == "/" {
+			query := page.Query()
+			query.Set("page", "rss")
+			return []string{fmt.Sprintf("https://%s/?%s", page.Host, query.Encode())}
+		}
+	}
+
+	return nil
+}
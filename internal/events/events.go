@@ -0,0 +1,181 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"shinkan-rebirth/internal/models"
+)
+
+// Event is anything published on the bus. Subscribers switch on the
+// concrete type to pick the events they care about.
+type Event interface {
+	EventName() string
+}
+
+// Publisher is the side of the bus the checker and notifier depend on, so
+// they can be exercised with a fake that just records events
+type Publisher interface {
+	Publish(ctx context.Context, event Event)
+}
+
+// Handler processes a single event
+type Handler func(ctx context.Context, event Event)
+
+// CheckStarted is published when a run over all feeds begins
+type CheckStarted struct {
+	Job models.CheckJob
+}
+
+// CheckFinished is published when a run ends, including when cancelled
+type CheckFinished struct {
+	Job models.CheckJob
+}
+
+// FeedChecked is published after a successful check with the updated feed
+type FeedChecked struct {
+	Feed models.Feed
+}
+
+// ReleaseDetected is published when a feed has a new chapter or episode
+type ReleaseDetected struct {
+	Feed   models.Feed
+	Title  string
+	Link   string
+	Info   models.ReleaseInfo // Chapter, volume and group where the source knows them
+	Series *models.Series     // The series the feed is a source of, nil if it can't be read
+}
+
+// FeedFailed is published when a check fails after all retries
+type FeedFailed struct {
+	Feed       models.Feed
+	Error      string
+	Kind       models.ErrorKind
+	StatusCode int
+}
+
+// FeedRecovered is published when a feed that was failing checks fine again
+type FeedRecovered struct {
+	Feed          models.Feed
+	PreviousError string
+	FailCount     int
+}
+
+// FeedFetched is published for every HTTP request for a feed, including
+// retries and test notifications
+type FeedFetched struct {
+	URL      string
+	Duration time.Duration
+	Kind     models.ErrorKind // Empty on success
+}
+
+// NotificationSent is published for every delivery attempt on a channel
+type NotificationSent struct {
+	Feed    models.Feed
+	Channel string // "gotify" or "discord"
+	Test    bool
+	Error   string // Empty on success
+}
+
+// ReleaseDuplicate is published instead of a notification when another
+// feed of the same series already carried a release
+type ReleaseDuplicate struct {
+	Feed    models.Feed
+	Title   string
+	Sources []string // Names of every feed that carried the release, first one notified
+}
+
+// TorrentAdded is published for every release handed to a torrent client
+type TorrentAdded struct {
+	Feed   models.Feed
+	Client models.TorrentClient
+	Title  string
+	Error  string // Empty on success
+}
+
+func (CheckStarted) EventName() string     { return "check-started" }
+func (CheckFinished) EventName() string    { return "check-finished" }
+func (FeedChecked) EventName() string      { return "feed-checked" }
+func (ReleaseDetected) EventName() string  { return "release-detected" }
+func (FeedFailed) EventName() string       { return "feed-failed" }
+func (FeedRecovered) EventName() string    { return "feed-recovered" }
+func (FeedFetched) EventName() string      { return "feed-fetched" }
+func (NotificationSent) EventName() string { return "notification-sent" }
+func (TorrentAdded) EventName() string     { return "torrent-added" }
+func (ReleaseDuplicate) EventName() string { return "release-duplicate" }
+
+// Bus is an in-process publish/subscribe hub.
+//
+// Handlers registered with Handle run synchronously in the publisher's
+// goroutine, in registration order, so nothing they need is lost (e.g.
+// notifications). Channels from Subscribe are for best-effort consumers
+// like SSE clients: a subscriber that falls behind misses events rather
+// than stalling the checker.
+type Bus struct {
+	mu          sync.RWMutex
+	handlers    []Handler
+	subscribers map[int]chan Event
+	nextID      int
+}
+
+func New() *Bus {
+	return &Bus{
+		subscribers: make(map[int]chan Event),
+	}
+}
+
+// Publish delivers an event to every handler and subscriber
+func (b *Bus) Publish(ctx context.Context, event Event) {
+	// Copy under the lock so handlers may publish events themselves
+	b.mu.RLock()
+	handlers := make([]Handler, len(b.handlers))
+	copy(handlers, b.handlers)
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(ctx, event)
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// Subscriber is full, drop the event for it
+		}
+	}
+}
+
+// Handle registers a handler called for every published event
+func (b *Bus) Handle(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Subscribe returns a channel receiving published events and a function to
+// unsubscribe, which closes the channel
+func (b *Bus) Subscribe(buffer int) (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	ch := make(chan Event, buffer)
+	b.subscribers[id] = ch
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, id)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+
+	return ch, unsubscribe
+}
@@ -0,0 +1,63 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"shinkan-rebirth/internal/models"
+)
+
+func TestBusRunsHandlersInOrder(t *testing.T) {
+	bus := New()
+	var calls []string
+	bus.Handle(func(ctx context.Context, event Event) { calls = append(calls, "first "+event.EventName()) })
+	bus.Handle(func(ctx context.Context, event Event) { calls = append(calls, "second "+event.EventName()) })
+
+	bus.Publish(context.Background(), FeedChecked{Feed: models.Feed{ID: "1"}})
+
+	want := []string{"first feed-checked", "second feed-checked"}
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("calls[%d] = %q, want %q", i, calls[i], want[i])
+		}
+	}
+}
+
+func TestBusHandlersMayPublish(t *testing.T) {
+	bus := New()
+	var got []string
+	bus.Handle(func(ctx context.Context, event Event) {
+		got = append(got, event.EventName())
+		if _, ok := event.(ReleaseDetected); ok {
+			bus.Publish(ctx, NotificationSent{Channel: "gotify"})
+		}
+	})
+
+	bus.Publish(context.Background(), ReleaseDetected{Title: "Chapter 1"})
+
+	if len(got) != 2 || got[0] != "release-detected" || got[1] != "notification-sent" {
+		t.Fatalf("events = %v, want release-detected then notification-sent", got)
+	}
+}
+
+func TestSubscribeDropsEventsWhenFull(t *testing.T) {
+	bus := New()
+	ch, unsubscribe := bus.Subscribe(1)
+
+	bus.Publish(context.Background(), CheckStarted{})
+	bus.Publish(context.Background(), CheckFinished{}) // Buffer full, dropped
+
+	if event := <-ch; event.EventName() != "check-started" {
+		t.Errorf("first event = %s, want check-started", event.EventName())
+	}
+
+	unsubscribe()
+	unsubscribe() // Safe to call twice
+	if _, open := <-ch; open {
+		t.Error("channel still open after unsubscribe")
+	}
+	bus.Publish(context.Background(), CheckStarted{}) // No subscriber left to block or panic on
+}
@@ -0,0 +1,194 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"shinkan-rebirth/internal/models"
+)
+
+// AniList list statuses imported unless others are asked for
+var DefaultAniListStatuses = []string{"CURRENT", "PLANNING", "REPEATING"}
+
+const listQuery = `query ($user: String, $type: MediaType) {
+  MediaListCollection(userName: $user, type: $type) {
+    lists {
+      entries {
+        status
+        media { id idMal title { romaji english } siteUrl coverImage { large } }
+      }
+    }
+  }
+}`
+
+const malLookupQuery = `query ($ids: [Int], $type: MediaType, $page: Int) {
+  Page(page: $page, perPage: 50) {
+    media(idMal_in: $ids, type: $type) { id idMal title { romaji english } siteUrl coverImage { large } }
+  }
+}`
+
+type anilistMedia struct {
+	ID    int `json:"id"`
+	IDMal int `json:"idMal"`
+	Title struct {
+		Romaji  string `json:"romaji"`
+		English string `json:"english"`
+	} `json:"title"`
+	SiteURL    string `json:"siteUrl"`
+	CoverImage struct {
+		Large string `json:"large"`
+	} `json:"coverImage"`
+}
+
+func (m anilistMedia) title() string {
+	if m.Title.English != "" {
+		return m.Title.English
+	}
+	return m.Title.Romaji
+}
+
+type graphQLResponse struct {
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (r graphQLResponse) err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	return fmt.Errorf("AniList: %s", r.Errors[0].Message)
+}
+
+// FromAniList imports a user's public anime or manga list. Only entries with
+// one of the given statuses are included (DefaultAniListStatuses if none).
+func (i *Importer) FromAniList(ctx context.Context, username string, feedType models.FeedType, statuses []string) ([]Candidate, error) {
+	if username == "" {
+		return nil, fmt.Errorf("AniList username required")
+	}
+	if len(statuses) == 0 {
+		statuses = DefaultAniListStatuses
+	}
+
+	wanted := make(map[string]bool)
+	for _, status := range statuses {
+		wanted[strings.ToUpper(status)] = true
+	}
+
+	var resp struct {
+		graphQLResponse
+		Data struct {
+			MediaListCollection struct {
+				Lists []struct {
+					Entries []struct {
+						Status string       `json:"status"`
+						Media  anilistMedia `json:"media"`
+					} `json:"entries"`
+				} `json:"lists"`
+			} `json:"MediaListCollection"`
+		} `json:"data"`
+	}
+
+	err := i.postJSON(ctx, anilistAPI, map[string]interface{}{
+		"query":     listQuery,
+		"variables": map[string]interface{}{"user": username, "type": anilistType(feedType)},
+	}, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch AniList list: %w", err)
+	}
+	if err := resp.err(); err != nil {
+		return nil, err
+	}
+
+	candidates := make([]Candidate, 0)
+	seen := make(map[int]bool)
+	for _, list := range resp.Data.MediaListCollection.Lists {
+		for _, entry := range list.Entries {
+			// Custom lists repeat entries from the status lists
+			if !wanted[entry.Status] || seen[entry.Media.ID] {
+				continue
+			}
+			seen[entry.Media.ID] = true
+
+			candidate, err := i.fromAniListMedia(ctx, entry.Media, feedType)
+			if err != nil {
+				return nil, err
+			}
+			candidate.Status = entry.Status
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	return candidates, nil
+}
+
+// fromAniListMedia builds a candidate for an AniList entry. Manga are
+// matched to a MangaDex series for the RSS URL.
+func (i *Importer) fromAniListMedia(ctx context.Context, media anilistMedia, feedType models.FeedType) (Candidate, error) {
+	var candidate Candidate
+
+	if feedType == models.FeedTypeAnime {
+		candidate.Feed = i.animeFeed(media.title())
+	} else {
+		series, err := i.findMangaDexSeries(ctx, media.title(), media.ID, media.IDMal)
+		if err != nil {
+			return Candidate{}, err
+		}
+		candidate.Feed = models.Feed{Name: media.title(), Type: models.FeedTypeManga}
+		if series != nil {
+			candidate.Feed.RSSUrl = series.rssURL()
+		} else {
+			candidate.Warning = "No MangaDex series found, add an RSS URL manually"
+		}
+	}
+
+	candidate.Feed.AnilistUrl = stringPtr(media.SiteURL)
+	candidate.Feed.Cover = stringPtr(media.CoverImage.Large)
+	return candidate, nil
+}
+
+// lookupMAL finds the AniList entries for MyAnimeList IDs
+func (i *Importer) lookupMAL(ctx context.Context, ids []int, feedType models.FeedType) (map[int]anilistMedia, error) {
+	found := make(map[int]anilistMedia)
+
+	for start := 0; start < len(ids); start += 50 {
+		end := start + 50
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		var resp struct {
+			graphQLResponse
+			Data struct {
+				Page struct {
+					Media []anilistMedia `json:"media"`
+				} `json:"Page"`
+			} `json:"data"`
+		}
+
+		err := i.postJSON(ctx, anilistAPI, map[string]interface{}{
+			"query":     malLookupQuery,
+			"variables": map[string]interface{}{"ids": ids[start:end], "type": anilistType(feedType), "page": 1},
+		}, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up AniList entries: %w", err)
+		}
+		if err := resp.err(); err != nil {
+			return nil, err
+		}
+
+		for _, media := range resp.Data.Page.Media {
+			found[media.IDMal] = media
+		}
+	}
+
+	return found, nil
+}
+
+func anilistType(feedType models.FeedType) string {
+	if feedType == models.FeedTypeAnime {
+		return "ANIME"
+	}
+	return "MANGA"
+}
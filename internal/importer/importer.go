@@ -0,0 +1,126 @@
+// Package importer turns lists from other services (AniList, MyAnimeList,
+// MangaDex) into feeds. Nothing is stored here: the results are previewed
+// in the UI and only the selected feeds are passed to Storage.ImportFeeds.
+package importer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"shinkan-rebirth/internal/models"
+)
+
+const (
+	anilistAPI  = "https://graphql.anilist.co"
+	mangadexAPI = "https://api.mangadex.org"
+
+	// DefaultAnimeRSS is a Nyaa search for English-translated anime, %s is
+	// replaced with the escaped title
+	DefaultAnimeRSS = "https://nyaa.si/?page=rss&q=%s&c=1_2&f=0"
+)
+
+// Candidate is a feed proposed by an importer. Feeds without an RSS URL
+// couldn't be matched and carry a warning instead.
+type Candidate struct {
+	Feed    models.Feed `json:"feed"`
+	Status  string      `json:"status,omitempty"` // Status on the source list, e.g. "CURRENT"
+	Warning string      `json:"warning,omitempty"`
+	Exists  bool        `json:"exists,omitempty"` // The user already has a feed with this RSS URL
+}
+
+type Importer struct {
+	httpClient *http.Client
+	animeRSS   string
+}
+
+// New creates an importer. animeRSS is the RSS URL template used for anime,
+// with %s standing for the title.
+func New(timeout time.Duration, animeRSS string) *Importer {
+	if animeRSS == "" {
+		animeRSS = DefaultAnimeRSS
+	}
+	return &Importer{
+		httpClient: &http.Client{Timeout: timeout},
+		animeRSS:   animeRSS,
+	}
+}
+
+// animeFeed builds the feed for an anime title
+func (i *Importer) animeFeed(title string) models.Feed {
+	return models.Feed{
+		Name:   title,
+		RSSUrl: fmt.Sprintf(i.animeRSS, url.QueryEscape(title)),
+		Type:   models.FeedTypeAnime,
+	}
+}
+
+// getJSON fetches a URL and decodes the JSON response into v
+func (i *Importer) getJSON(ctx context.Context, rawURL string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "Shinkan-Rebirth/1.0")
+
+	return i.do(req, v)
+}
+
+// postJSON sends body as JSON and decodes the JSON response into v
+func (i *Importer) postJSON(ctx context.Context, rawURL string, body, v interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", rawURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "Shinkan-Rebirth/1.0")
+
+	return i.do(req, v)
+}
+
+func (i *Importer) do(req *http.Request, v interface{}) error {
+	resp, err := i.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned status %d: %s", req.URL.Host, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// sleep waits between API calls to stay under rate limits
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func stringPtr(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
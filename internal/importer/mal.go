@@ -0,0 +1,109 @@
+package importer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"shinkan-rebirth/internal/models"
+)
+
+// MyAnimeList statuses imported unless others are asked for
+var DefaultMALStatuses = []string{"Watching", "Reading", "Plan to Watch", "Plan to Read"}
+
+type malExport struct {
+	Anime []struct {
+		ID     int    `xml:"series_animedb_id"`
+		Title  string `xml:"series_title"`
+		Status string `xml:"my_status"`
+	} `xml:"anime"`
+	Manga []struct {
+		ID     int    `xml:"manga_mangadb_id"`
+		Title  string `xml:"manga_title"`
+		Status string `xml:"my_status"`
+	} `xml:"manga"`
+}
+
+type malEntry struct {
+	id     int
+	title  string
+	status string
+}
+
+// FromMAL imports a MyAnimeList XML export (plain or gzipped, as downloaded).
+// Entries are matched to AniList for links and covers, and manga also to
+// MangaDex for the RSS URL.
+func (i *Importer) FromMAL(ctx context.Context, data []byte, statuses []string) ([]Candidate, error) {
+	if len(statuses) == 0 {
+		statuses = DefaultMALStatuses
+	}
+	wanted := make(map[string]bool)
+	for _, status := range statuses {
+		wanted[status] = true
+	}
+
+	var r io.Reader = bytes.NewReader(data)
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip file: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	var export malExport
+	if err := xml.NewDecoder(r).Decode(&export); err != nil {
+		return nil, fmt.Errorf("invalid MyAnimeList export: %w", err)
+	}
+
+	var feedType models.FeedType
+	var entries []malEntry
+	if len(export.Anime) > 0 {
+		feedType = models.FeedTypeAnime
+		for _, a := range export.Anime {
+			entries = append(entries, malEntry{a.ID, a.Title, a.Status})
+		}
+	} else {
+		feedType = models.FeedTypeManga
+		for _, m := range export.Manga {
+			entries = append(entries, malEntry{m.ID, m.Title, m.Status})
+		}
+	}
+
+	selected := make([]malEntry, 0, len(entries))
+	ids := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		if wanted[entry.status] {
+			selected = append(selected, entry)
+			ids = append(ids, entry.id)
+		}
+	}
+
+	anilist, err := i.lookupMAL(ctx, ids, feedType)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]Candidate, 0, len(selected))
+	for _, entry := range selected {
+		media, ok := anilist[entry.id]
+		if !ok {
+			// Not on AniList, go by the MAL title alone
+			media = anilistMedia{IDMal: entry.id}
+			media.Title.Romaji = entry.title
+		}
+
+		candidate, err := i.fromAniListMedia(ctx, media, feedType)
+		if err != nil {
+			return nil, err
+		}
+		candidate.Status = entry.status
+		candidates = append(candidates, candidate)
+	}
+
+	return candidates, nil
+}
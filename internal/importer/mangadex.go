@@ -0,0 +1,218 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"shinkan-rebirth/internal/models"
+)
+
+// MangaDex allows about five requests a second
+const mangadexDelay = 250 * time.Millisecond
+
+var uuidPattern = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+
+type mangadexManga struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	Attributes struct {
+		Title     map[string]string   `json:"title"`
+		AltTitles []map[string]string `json:"altTitles"`
+		Links     map[string]string   `json:"links"`
+	} `json:"attributes"`
+	Relationships []struct {
+		Type       string `json:"type"`
+		Attributes *struct {
+			FileName string `json:"fileName"`
+		} `json:"attributes"`
+	} `json:"relationships"`
+}
+
+func (m mangadexManga) title() string {
+	if title := m.Attributes.Title["en"]; title != "" {
+		return title
+	}
+	for _, title := range m.Attributes.Title {
+		return title
+	}
+	return m.ID
+}
+
+// hasTitle reports whether any of the series' titles matches, ignoring case
+func (m mangadexManga) hasTitle(title string) bool {
+	for _, t := range m.Attributes.Title {
+		if strings.EqualFold(t, title) {
+			return true
+		}
+	}
+	for _, alt := range m.Attributes.AltTitles {
+		for _, t := range alt {
+			if strings.EqualFold(t, title) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (m mangadexManga) rssURL() string {
+	return fmt.Sprintf("https://mangadex.org/title/%s/rss", m.ID)
+}
+
+func (m mangadexManga) coverURL() string {
+	for _, rel := range m.Relationships {
+		if rel.Type == "cover_art" && rel.Attributes != nil && rel.Attributes.FileName != "" {
+			return fmt.Sprintf("https://uploads.mangadex.org/covers/%s/%s.256.jpg", m.ID, rel.Attributes.FileName)
+		}
+	}
+	return ""
+}
+
+func (m mangadexManga) feed() models.Feed {
+	feed := models.Feed{
+		Name:   m.title(),
+		RSSUrl: m.rssURL(),
+		Type:   models.FeedTypeManga,
+		Cover:  stringPtr(m.coverURL()),
+	}
+	if al := m.Attributes.Links["al"]; al != "" {
+		feed.AnilistUrl = stringPtr("https://anilist.co/manga/" + al)
+	}
+	return feed
+}
+
+type mangadexList struct {
+	Result string          `json:"result"`
+	Data   []mangadexManga `json:"data"`
+}
+
+// FromMangaDex imports MangaDex follows. data is either the JSON returned by
+// the /user/follows/manga API (with includes[]=cover_art), or any text
+// containing series IDs or title URLs, such as one URL per line.
+func (i *Importer) FromMangaDex(ctx context.Context, data []byte) ([]Candidate, error) {
+	var series []mangadexManga
+
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		var list mangadexList
+		if err := json.Unmarshal(trimmed, &list); err != nil {
+			return nil, fmt.Errorf("invalid MangaDex export: %w", err)
+		}
+		series = list.Data
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		if err := json.Unmarshal(trimmed, &series); err != nil {
+			return nil, fmt.Errorf("invalid MangaDex export: %w", err)
+		}
+	default:
+		ids := uniqueIDs(uuidPattern.FindAllString(strings.ToLower(string(trimmed)), -1))
+		if len(ids) == 0 {
+			return nil, fmt.Errorf("no MangaDex series IDs found")
+		}
+
+		var err error
+		series, err = i.fetchMangaDexSeries(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	candidates := make([]Candidate, 0, len(series))
+	for _, manga := range series {
+		if manga.Type != "" && manga.Type != "manga" {
+			continue
+		}
+		candidates = append(candidates, Candidate{Feed: manga.feed()})
+	}
+
+	return candidates, nil
+}
+
+// fetchMangaDexSeries looks up series by ID, 100 at a time
+func (i *Importer) fetchMangaDexSeries(ctx context.Context, ids []string) ([]mangadexManga, error) {
+	series := make([]mangadexManga, 0, len(ids))
+
+	for start := 0; start < len(ids); start += 100 {
+		end := start + 100
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		query := url.Values{}
+		query.Set("limit", "100")
+		query.Add("includes[]", "cover_art")
+		for _, rating := range []string{"safe", "suggestive", "erotica", "pornographic"} {
+			query.Add("contentRating[]", rating)
+		}
+		for _, id := range ids[start:end] {
+			query.Add("ids[]", id)
+		}
+
+		var list mangadexList
+		if err := i.getJSON(ctx, mangadexAPI+"/manga?"+query.Encode(), &list); err != nil {
+			return nil, fmt.Errorf("failed to fetch MangaDex series: %w", err)
+		}
+		series = append(series, list.Data...)
+
+		if err := sleep(ctx, mangadexDelay); err != nil {
+			return nil, err
+		}
+	}
+
+	return series, nil
+}
+
+// findMangaDexSeries searches MangaDex for a series by title. A result is
+// only accepted if it links back to the same AniList or MyAnimeList entry,
+// or has exactly the same title.
+func (i *Importer) findMangaDexSeries(ctx context.Context, title string, anilistID, malID int) (*mangadexManga, error) {
+	query := url.Values{}
+	query.Set("title", title)
+	query.Set("limit", "10")
+	query.Add("includes[]", "cover_art")
+
+	var list mangadexList
+	if err := i.getJSON(ctx, mangadexAPI+"/manga?"+query.Encode(), &list); err != nil {
+		return nil, fmt.Errorf("failed to search MangaDex: %w", err)
+	}
+
+	if err := sleep(ctx, mangadexDelay); err != nil {
+		return nil, err
+	}
+
+	for j := range list.Data {
+		links := list.Data[j].Attributes.Links
+		if anilistID != 0 && links["al"] == fmt.Sprint(anilistID) {
+			return &list.Data[j], nil
+		}
+		if malID != 0 && links["mal"] == fmt.Sprint(malID) {
+			return &list.Data[j], nil
+		}
+	}
+
+	for j := range list.Data {
+		if list.Data[j].hasTitle(title) {
+			return &list.Data[j], nil
+		}
+	}
+
+	return nil, nil
+}
+
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool)
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
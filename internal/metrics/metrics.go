@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"time"
+
+	"shinkan-rebirth/internal/events"
+	"shinkan-rebirth/internal/models"
+)
+
+// Metrics collects Prometheus metrics from bus events and storage writes.
+// Feed gauges are computed from the feed list on every scrape.
+type Metrics struct {
+	checks        *CounterVec
+	fetchDuration *HistogramVec
+	notifications *CounterVec
+	storageWrites *HistogramVec
+}
+
+func New() *Metrics {
+	return &Metrics{
+		checks: NewCounterVec("shinkan_checks_total",
+			"Feed checks by result and error kind.", "result", "error_kind"),
+		fetchDuration: NewHistogramVec("shinkan_fetch_duration_seconds",
+			"Time taken to fetch a feed, per host.", DefaultBuckets, "host", "result"),
+		notifications: NewCounterVec("shinkan_notifications_total",
+			"Notification deliveries by channel and outcome.", "channel", "outcome", "test"),
+		storageWrites: NewHistogramVec("shinkan_storage_write_duration_seconds",
+			"Time taken to write the feed data files.", DefaultBuckets),
+	}
+}
+
+func (m *Metrics) HandleEvent(ctx context.Context, event events.Event) {
+	switch e := event.(type) {
+	case events.FeedChecked:
+		// Soft failures like no_match still count as a successful check
+		m.checks.Inc("success", errorKindLabel(e.Feed.ErrorKind))
+	case events.FeedFailed:
+		m.checks.Inc("failure", errorKindLabel(e.Kind))
+	case events.FeedFetched:
+		result := "success"
+		if e.Kind != "" {
+			result = string(e.Kind)
+		}
+		m.fetchDuration.Observe(e.Duration.Seconds(), hostOf(e.URL), result)
+	case events.NotificationSent:
+		outcome := "success"
+		if e.Error != "" {
+			outcome = "failure"
+		}
+		test := "false"
+		if e.Test {
+			test = "true"
+		}
+		m.notifications.Inc(e.Channel, outcome, test)
+	}
+}
+
+// ObserveStorageWrite records how long a storage write took
+func (m *Metrics) ObserveStorageWrite(d time.Duration) {
+	m.storageWrites.Observe(d.Seconds())
+}
+
+// Write renders all metrics in the Prometheus text format
+func (m *Metrics) Write(w io.Writer, feeds []models.Feed) {
+	m.checks.write(w)
+	m.fetchDuration.write(w)
+	m.notifications.write(w)
+	m.storageWrites.write(w)
+	writeFeedGauges(w, feeds)
+}
+
+func writeFeedGauges(w io.Writer, feeds []models.Feed) {
+	counts := make(map[string]float64)
+	lastSuccess := make([]gauge, 0, len(feeds))
+
+	for _, feed := range feeds {
+		category := feed.Category
+		if category == "" {
+			category = "Uncategorized"
+		}
+		counts[labelString(
+			[]string{"type", "category", "state"},
+			[]string{string(feed.Type), category, feedState(feed)},
+		)]++
+
+		if feed.LastSuccess == nil {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, *feed.LastSuccess)
+		if err != nil {
+			continue
+		}
+		lastSuccess = append(lastSuccess, gauge{
+			labels: labelString(
+				[]string{"feed_id", "feed", "owner"},
+				[]string{feed.ID, feed.Name, feed.OwnerID},
+			),
+			value: float64(t.Unix()),
+		})
+	}
+
+	feedCounts := make([]gauge, 0, len(counts))
+	for labels, count := range counts {
+		feedCounts = append(feedCounts, gauge{labels: labels, value: count})
+	}
+
+	writeGauges(w, "shinkan_feeds", "Number of feeds by type, category and state.", feedCounts)
+	writeGauges(w, "shinkan_feed_last_success_timestamp_seconds",
+		"Unix time of the last successful check of each feed.", lastSuccess)
+}
+
+// feedState summarises a feed for the shinkan_feeds gauge
+func feedState(feed models.Feed) string {
+	switch {
+	case feed.LastChecked == nil:
+		return "never_checked"
+	case feed.FailCount > 0:
+		return "failing"
+	case feed.ErrorKind == models.ErrorKindNoMatch:
+		return "no_match"
+	default:
+		return "ok"
+	}
+}
+
+func errorKindLabel(kind models.ErrorKind) string {
+	if kind == "" {
+		return "none"
+	}
+	return string(kind)
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "unknown"
+	}
+	return u.Host
+}
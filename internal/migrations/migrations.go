@@ -0,0 +1,202 @@
+// Package migrations upgrades data files written by older versions of
+// Shinkan to the current schema.
+//
+// Migrations work on the raw JSON document rather than models.Storage so
+// they keep working however the models change later. Each one upgrades a
+// document by exactly one version.
+package migrations
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"shinkan-rebirth/internal/models"
+)
+
+type document map[string]interface{}
+
+// Migration upgrades a document from Version-1 to Version
+type Migration struct {
+	Version     int
+	Description string
+	apply       func(doc document) error
+	// dataOnly migrations are skipped for imports, storage.Import does
+	// their work when the feeds are stored
+	dataOnly bool
+}
+
+// All migrations in order. The last one's version must equal
+// models.SchemaVersion.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "Convert the legacy mangas list to feeds",
+		apply:       legacyMangas,
+	},
+	{
+		Version:     2,
+		Description: "Assign feeds to the default user",
+		apply:       defaultOwner,
+	},
+	{
+		Version:     3,
+		Description: "Give every feed a series",
+		apply:       feedSeries,
+		dataOnly:    true,
+	},
+}
+
+func init() {
+	if last := migrations[len(migrations)-1].Version; last != models.SchemaVersion {
+		panic(fmt.Sprintf("migrations end at version %d, models.SchemaVersion is %d", last, models.SchemaVersion))
+	}
+}
+
+// Result describes the migration of one document
+type Result struct {
+	Path    string      `json:"path,omitempty"`
+	From    int         `json:"from"`
+	To      int         `json:"to"`
+	Applied []Migration `json:"applied"`
+	Backup  string      `json:"backup,omitempty"`
+}
+
+// Migrate upgrades a JSON document to the current schema version and
+// returns the upgraded document. Documents that are already current are
+// returned unchanged.
+func Migrate(raw []byte) ([]byte, Result, error) {
+	return migrate(raw, false)
+}
+
+// MigrateImport upgrades an export or import body like Migrate, without
+// creating series for its feeds. Series made up from an old export could
+// otherwise take over series the user already has.
+func MigrateImport(raw []byte) ([]byte, Result, error) {
+	return migrate(raw, true)
+}
+
+func migrate(raw []byte, forImport bool) ([]byte, Result, error) {
+	var doc document
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, Result{}, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	from, err := version(doc)
+	if err != nil {
+		return nil, Result{}, err
+	}
+
+	result := Result{From: from, To: from, Applied: []Migration{}}
+	if from > models.SchemaVersion {
+		return nil, result, fmt.Errorf("schema version %d is newer than this Shinkan supports (%d)", from, models.SchemaVersion)
+	}
+	if from == models.SchemaVersion {
+		return raw, result, nil
+	}
+
+	for _, m := range migrations {
+		if m.Version <= from {
+			continue
+		}
+		if m.dataOnly && forImport {
+			doc["version"] = m.Version
+			result.To = m.Version
+			continue
+		}
+		if err := m.apply(doc); err != nil {
+			return nil, result, fmt.Errorf("migration %d (%s): %w", m.Version, m.Description, err)
+		}
+		doc["version"] = m.Version
+		result.To = m.Version
+		result.Applied = append(result.Applied, m)
+	}
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, result, err
+	}
+	return out, result, nil
+}
+
+// MigrateFile upgrades a data file in place, first copying the original to
+// a backup next to it. With dryRun nothing is written. Missing files are
+// left alone, they're created in the current schema.
+func MigrateFile(path string, dryRun bool) (Result, error) {
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Result{Path: path, From: models.SchemaVersion, To: models.SchemaVersion, Applied: []Migration{}}, nil
+	}
+	if err != nil {
+		return Result{Path: path}, err
+	}
+
+	out, result, err := Migrate(raw)
+	result.Path = path
+	if err != nil {
+		return result, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(result.Applied) == 0 || dryRun {
+		return result, nil
+	}
+
+	result.Backup = fmt.Sprintf("%s.v%d-%s.bak", path, result.From, time.Now().Format("20060102-150405"))
+	if err := os.WriteFile(result.Backup, raw, 0644); err != nil {
+		return result, fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+
+	// Write to a temporary file first so a crash can't leave half a file
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(out, '\n'), 0644); err != nil {
+		return result, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// version reads the schema version of a document. Data files from before
+// versioning have none; exports used "1.0".
+func version(doc document) (int, error) {
+	switch v := doc["version"].(type) {
+	case nil:
+		if _, ok := doc["mangas"]; ok {
+			return 0, nil
+		}
+		return 1, nil
+	case float64:
+		return int(v), nil
+	case string:
+		major, _, _ := strings.Cut(v, ".")
+		n, err := strconv.Atoi(major)
+		if err != nil {
+			return 0, fmt.Errorf("invalid schema version %q", v)
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("invalid schema version %v", v)
+	}
+}
+
+// feeds returns the feed objects of a document
+func feeds(doc document) []map[string]interface{} {
+	list, _ := doc["feeds"].([]interface{})
+	result := make([]map[string]interface{}, 0, len(list))
+	for _, item := range list {
+		if feed, ok := item.(map[string]interface{}); ok {
+			result = append(result, feed)
+		}
+	}
+	return result
+}
+
+func setDefault(feed map[string]interface{}, key string, value interface{}) {
+	if _, ok := feed[key]; !ok {
+		feed[key] = value
+	}
+}
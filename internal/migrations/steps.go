@@ -0,0 +1,97 @@
+package migrations
+
+import (
+	"shinkan-rebirth/internal/models"
+)
+
+// legacyMangas converts the original single list of manga,
+// {"mangas": [...]}, to the feeds layout
+func legacyMangas(doc document) error {
+	mangas, ok := doc["mangas"].([]interface{})
+	if !ok {
+		if _, exists := doc["feeds"]; !exists {
+			doc["feeds"] = []interface{}{}
+		}
+		return nil
+	}
+
+	for _, item := range mangas {
+		if feed, ok := item.(map[string]interface{}); ok {
+			setDefault(feed, "type", string(models.FeedTypeManga))
+			setDefault(feed, "category", "Uncategorized")
+			setDefault(feed, "addedAt", "")
+		}
+	}
+
+	doc["feeds"] = mangas
+	delete(doc, "mangas")
+	return nil
+}
+
+// defaultOwner gives feeds from before user accounts to the built-in admin
+func defaultOwner(doc document) error {
+	for _, feed := range feeds(doc) {
+		if owner, _ := feed["ownerId"].(string); owner == "" {
+			feed["ownerId"] = models.DefaultUserID
+		}
+	}
+	return nil
+}
+
+// feedSeries creates a series for every feed. Feeds already sharing a
+// seriesId become one series named after the first of them; the others
+// get a series of their own with the feed's ID. Feeds without an ID, only
+// found in hand-written imports, are left to get a series when stored.
+func feedSeries(doc document) error {
+	list, _ := doc["series"].([]interface{})
+	created := make(map[string]bool)
+
+	for _, feed := range feeds(doc) {
+		owner, _ := feed["ownerId"].(string)
+		id, _ := feed["seriesId"].(string)
+		if id == "" {
+			id, _ = feed["id"].(string)
+			if id == "" {
+				continue
+			}
+			feed["seriesId"] = id
+		}
+
+		key := owner + "\x00" + id
+		if created[key] {
+			continue
+		}
+		created[key] = true
+
+		series := map[string]interface{}{
+			"id":        id,
+			"ownerId":   owner,
+			"title":     feed["name"],
+			"type":      string(models.FeedTypeManga),
+			"status":    string(models.SeriesCurrent),
+			"category":  "Uncategorized",
+			"createdAt": feed["addedAt"],
+		}
+		if feedType, _ := feed["type"].(string); feedType != "" {
+			series["type"] = feedType
+		}
+		if category, _ := feed["category"].(string); category != "" {
+			series["category"] = category
+		}
+		if url, _ := feed["anilistUrl"].(string); url != "" {
+			if anilistID := models.ParseAnilistID(url); anilistID != 0 {
+				series["anilistId"] = anilistID
+			}
+		}
+		if cover, _ := feed["cover"].(string); cover != "" {
+			series["cover"] = cover
+		}
+		list = append(list, series)
+	}
+
+	if list == nil {
+		list = []interface{}{}
+	}
+	doc["series"] = list
+	return nil
+}
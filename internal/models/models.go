@@ -1,5 +1,11 @@
 package models
 
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
 // FeedType represents the type of feed (manga or anime)
 type FeedType string
 
@@ -8,40 +14,406 @@ const (
 	FeedTypeAnime FeedType = "anime"
 )
 
+// ErrorKind classifies why a feed check failed
+type ErrorKind string
+
+const (
+	ErrorKindDNS        ErrorKind = "dns"
+	ErrorKindTimeout    ErrorKind = "timeout"
+	ErrorKindTLS        ErrorKind = "tls"
+	ErrorKindNetwork    ErrorKind = "network"
+	ErrorKindHTTP4xx    ErrorKind = "http_4xx"
+	ErrorKindHTTP5xx    ErrorKind = "http_5xx"
+	ErrorKindCloudflare ErrorKind = "cloudflare"
+	ErrorKindParse      ErrorKind = "parse"
+	ErrorKindEmpty      ErrorKind = "empty"
+	ErrorKindNoMatch    ErrorKind = "no_match"
+	ErrorKindConfig     ErrorKind = "config" // The feed's source settings are invalid
+	ErrorKindUnknown    ErrorKind = "unknown"
+)
+
 // Feed represents a manga or anime RSS feed to monitor
 type Feed struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	RSSUrl      string    `json:"rssUrl"`
-	Type        FeedType  `json:"type"` // "manga" or "anime"
-	AnilistUrl  *string   `json:"anilistUrl,omitempty"`
-	Category    string    `json:"category"`
-	LastChecked *string   `json:"lastChecked"`
-	LastChapter *string   `json:"lastChapter"`
-	LastError   *string   `json:"lastError"`
-	FailCount   int       `json:"failCount"`
-	AddedAt     string    `json:"addedAt"`
-	SearchText  *string   `json:"searchText,omitempty"` // For anime: text to search for (e.g., "Dragon Raja")
-	Cover       *string   `json:"cover,omitempty"` // Cover image URL for Discord embeds
+	ID          string              `json:"id"`
+	Name        string              `json:"name"`
+	RSSUrl      string              `json:"rssUrl"`
+	Type        FeedType            `json:"type"` // "manga" or "anime"
+	AnilistUrl  *string             `json:"anilistUrl,omitempty"`
+	Category    string              `json:"category"`
+	LastChecked *string             `json:"lastChecked"`
+	LastSuccess *string             `json:"lastSuccess,omitempty"` // Last check that didn't fail
+	LastChapter *string             `json:"lastChapter"`
+	LastItemID  *string             `json:"lastItemId,omitempty"` // ID of the last release, for sources with an ID path
+	LastError   *string             `json:"lastError"`
+	ErrorKind   ErrorKind           `json:"errorKind,omitempty"`   // Classified kind of LastError
+	ErrorStatus int                 `json:"errorStatus,omitempty"` // HTTP status code for http_4xx/http_5xx errors
+	FailCount   int                 `json:"failCount"`
+	AddedAt     string              `json:"addedAt"`
+	SearchText  *string             `json:"searchText,omitempty"`     // For anime: text to search for (e.g., "Dragon Raja")
+	Cover       *string             `json:"cover,omitempty"`          // Cover image URL for Discord embeds
+	OwnerID     string              `json:"ownerId,omitempty"`        // User the feed belongs to
+	Source      *Source             `json:"source,omitempty"`         // How RSSUrl is read, RSS if not set
+	Torrent     *TorrentHandoff     `json:"torrent,omitempty"`        // Torrent client new releases are sent to
+	Preferences *ReleasePreferences `json:"preferences,omitempty"`    // For anime: which release of an episode to pick
+	LastEpisode *string             `json:"lastEpisode,omitempty"`    // Last episode notified, with preferences, e.g. "5" or "S2E1"
+	Pending     *PendingEpisode     `json:"pendingEpisode,omitempty"` // New episode held for the grace period
+	SeriesID    string              `json:"seriesId,omitempty"`       // The series the feed is a source of
+}
+
+// SeriesStatus is where the user is with a series, as on AniList
+type SeriesStatus string
+
+const (
+	SeriesCurrent   SeriesStatus = "current" // Reading or watching
+	SeriesPlanning  SeriesStatus = "planning"
+	SeriesPaused    SeriesStatus = "paused"
+	SeriesCompleted SeriesStatus = "completed"
+	SeriesDropped   SeriesStatus = "dropped" // Still checked, new releases aren't notified
+)
+
+// Valid reports whether s is a known status
+func (s SeriesStatus) Valid() bool {
+	switch s {
+	case SeriesCurrent, SeriesPlanning, SeriesPaused, SeriesCompleted, SeriesDropped:
+		return true
+	}
+	return false
+}
+
+// Series is a manga or anime followed through one or more feeds, its
+// sources. Feeds point to it with their SeriesID.
+type Series struct {
+	ID        string       `json:"id"`
+	OwnerID   string       `json:"ownerId,omitempty"`
+	Title     string       `json:"title"`
+	Type      FeedType     `json:"type"`
+	Aliases   []string     `json:"aliases,omitempty"` // Other titles, e.g. romaji or English
+	AnilistID int          `json:"anilistId,omitempty"`
+	Cover     string       `json:"cover,omitempty"`
+	Status    SeriesStatus `json:"status"`
+	Category  string       `json:"category"`
+	Progress  string       `json:"progress,omitempty"` // Last chapter or episode read, e.g. "12"
+	CreatedAt string       `json:"createdAt"`
+}
+
+// AnilistURL links to the series on AniList, empty without an AniList ID
+func (s Series) AnilistURL() string {
+	if s.AnilistID == 0 {
+		return ""
+	}
+	kind := "manga"
+	if s.Type == FeedTypeAnime {
+		kind = "anime"
+	}
+	return fmt.Sprintf("https://anilist.co/%s/%d", kind, s.AnilistID)
+}
+
+var seriesIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// ValidSeriesID reports whether id can name a series: letters, digits,
+// '.', '_' and '-', so slugs like "solo-leveling" work
+func ValidSeriesID(id string) bool {
+	return seriesIDPattern.MatchString(id)
+}
+
+var anilistURLPattern = regexp.MustCompile(`anilist\.co/(?:anime|manga)/(\d+)`)
+
+// ParseAnilistID reads the ID from an AniList URL such as
+// https://anilist.co/manga/30013/Oyasumi-Punpun, 0 if there is none
+func ParseAnilistID(url string) int {
+	m := anilistURLPattern.FindStringSubmatch(url)
+	if m == nil {
+		return 0
+	}
+	id, _ := strconv.Atoi(m[1])
+	return id
+}
+
+// SeriesView is a series with its feeds, as the API lists it
+type SeriesView struct {
+	Series
+	Feeds  []Feed `json:"feeds"`
+	Latest string `json:"latest,omitempty"` // Newest chapter or episode number across the feeds
+	Unread bool   `json:"unread"`           // Latest is past Progress
+}
+
+// ReleasePreferences pick one release per episode when several groups
+// upload the same episode. Groups outrank resolution, which outranks codec.
+type ReleasePreferences struct {
+	Groups      []string `json:"groups,omitempty"`      // Preferred release groups, best first
+	Resolution  string   `json:"resolution,omitempty"`  // e.g. 1080p
+	Codec       string   `json:"codec,omitempty"`       // e.g. HEVC or x264
+	GracePeriod string   `json:"gracePeriod,omitempty"` // How long to wait for a better release, e.g. 2h
+}
+
+// PendingEpisode is a new episode whose best release isn't out yet
+type PendingEpisode struct {
+	Episode string `json:"episode"`
+	Since   string `json:"since"` // When the episode was first seen
+}
+
+// TorrentClient is a torrent client new releases can be handed to
+type TorrentClient string
+
+const (
+	TorrentClientQBittorrent  TorrentClient = "qbittorrent"
+	TorrentClientTransmission TorrentClient = "transmission"
+)
+
+// TorrentHandoff sends a feed's new releases to a torrent client
+type TorrentHandoff struct {
+	Client   TorrentClient `json:"client"`
+	SavePath string        `json:"savePath,omitempty"` // Client default if empty
+	Category string        `json:"category,omitempty"` // qBittorrent category or Transmission label
+}
+
+// SourceKind is the format a feed's URL is read as
+type SourceKind string
+
+const (
+	SourceRSS      SourceKind = "rss"
+	SourceHTML     SourceKind = "html"     // A web page read with CSS selectors
+	SourceJSON     SourceKind = "json"     // A JSON API read with paths
+	SourceMangaDex SourceKind = "mangadex" // The MangaDex chapter API
+)
+
+// Source configures how a feed is read, for sites without RSS. Only the
+// settings for its kind are used.
+type Source struct {
+	Kind     SourceKind      `json:"kind"`
+	HTML     *HTMLSource     `json:"html,omitempty"`
+	JSON     *JSONSource     `json:"json,omitempty"`
+	MangaDex *MangaDexSource `json:"mangadex,omitempty"`
+}
+
+// MangaDexSource reads a series' chapters from the MangaDex API
+type MangaDexSource struct {
+	MangaID   string   `json:"mangaId,omitempty"`   // Taken from the feed URL if empty
+	Languages []string `json:"languages,omitempty"` // Translated languages, e.g. en, pt-br; all if empty
+	Groups    []string `json:"groups,omitempty"`    // Scanlation group names or IDs; all if empty
+}
+
+// JSONSource extracts releases from a JSON API. Paths look like
+// data.items[0].title; fields other than Items are relative to each item
+// and may instead be a template such as "Chapter {attributes.chapter}".
+type JSONSource struct {
+	Headers    map[string]string `json:"headers,omitempty"`    // Sent with the request, e.g. API keys
+	Items      string            `json:"items"`                // Path to the list of releases, empty if the response is the list
+	Title      string            `json:"title"`                // Path or template of the release title
+	Link       string            `json:"link,omitempty"`       // Path or template of the release URL
+	ID         string            `json:"id,omitempty"`         // Path of a unique ID, usable in templates as {id}
+	Date       string            `json:"date,omitempty"`       // Path of the release date, text or a Unix timestamp
+	DateFormat string            `json:"dateFormat,omitempty"` // Go time layout of the date, common formats are tried if empty
+	Reverse    bool              `json:"reverse,omitempty"`    // The list has the oldest release first
+}
+
+// SourceKind returns the kind of a feed's source
+func (f Feed) SourceKind() SourceKind {
+	if f.Source == nil || f.Source.Kind == "" {
+		return SourceRSS
+	}
+	return f.Source.Kind
+}
+
+// HTMLSource extracts releases from a web page. Selectors other than Item
+// are matched within each item.
+type HTMLSource struct {
+	Item       string `json:"item"`                 // One element per release
+	Title      string `json:"title,omitempty"`      // Text of the release, the item's own text if empty
+	Link       string `json:"link,omitempty"`       // Element with the href, the first link if empty
+	Date       string `json:"date,omitempty"`       // Element with the release date, optional
+	DateFormat string `json:"dateFormat,omitempty"` // Go time layout of the date, common formats are tried if empty
+	Reverse    bool   `json:"reverse,omitempty"`    // The page lists the oldest release first
+}
+
+// DefaultUserID owns feeds created before user accounts existed. It is the
+// built-in admin account, and the only user when authentication is disabled.
+const DefaultUserID = "admin"
+
+// NotificationTargets are a user's own delivery channels. Empty fields fall
+// back to the globally configured Gotify and Discord settings.
+type NotificationTargets struct {
+	GotifyServer     string `json:"gotifyServer,omitempty"`
+	GotifyToken      string `json:"gotifyToken,omitempty"`
+	DiscordChannelID string `json:"discordChannelId,omitempty"`
+}
+
+// User is an account with its own feeds and notification targets
+type User struct {
+	ID           string              `json:"id"`
+	Username     string              `json:"username"`
+	PasswordHash string              `json:"passwordHash,omitempty"`
+	IsAdmin      bool                `json:"isAdmin"`
+	Targets      NotificationTargets `json:"targets"`
+	CreatedAt    string              `json:"createdAt"`
 }
 
 // Storage represents the data structure for storing feeds
+// SchemaVersion is the version of the data file layout written by this
+// build. Older files are upgraded by the migrations package.
+const SchemaVersion = 3
+
 type Storage struct {
-	Feeds []Feed `json:"feeds"`
+	Version int      `json:"version"`
+	Feeds   []Feed   `json:"feeds"`
+	Series  []Series `json:"series"`
 }
 
 // Stats represents runtime statistics
 type Stats struct {
-	TotalChecks       int       `json:"totalChecks"`
-	SuccessfulChecks  int       `json:"successfulChecks"`
-	FailedChecks      int       `json:"failedChecks"`
-	NotificationsSent int       `json:"notificationsSent"`
-	LastCheckTime     *string   `json:"lastCheckTime"`
-	TotalFeeds        int       `json:"totalFeeds"`
-	FeedsWithErrors   int       `json:"feedsWithErrors"`
-	FeedsNeverChecked int       `json:"feedsNeverChecked"`
-	Categories        int       `json:"categories"`
-	Uptime            int64     `json:"uptime"`
+	TotalChecks       int               `json:"totalChecks"`
+	SuccessfulChecks  int               `json:"successfulChecks"`
+	FailedChecks      int               `json:"failedChecks"`
+	NotificationsSent int               `json:"notificationsSent"`
+	LastCheckTime     *string           `json:"lastCheckTime"`
+	TotalFeeds        int               `json:"totalFeeds"`
+	FeedsWithErrors   int               `json:"feedsWithErrors"`
+	FeedsNeverChecked int               `json:"feedsNeverChecked"`
+	Categories        int               `json:"categories"`
+	ErrorKinds        map[ErrorKind]int `json:"errorKinds"`
+	Uptime            int64             `json:"uptime"`
+}
+
+// JobStatus is the state of a check run
+type JobStatus string
+
+const (
+	JobRunning   JobStatus = "running"
+	JobFinished  JobStatus = "finished"
+	JobCancelled JobStatus = "cancelled"
+	JobFailed    JobStatus = "failed" // The run couldn't start, e.g. the data files are unreadable
+)
+
+// CheckJob describes a single run over all feeds, or over one feed if
+// FeedID is set, and its progress
+type CheckJob struct {
+	ID          string    `json:"id"`
+	Trigger     string    `json:"trigger"`
+	FeedID      string    `json:"feedId,omitempty"`
+	Status      JobStatus `json:"status"`
+	Total       int       `json:"total"`
+	Done        int       `json:"done"`
+	Failed      int       `json:"failed"`
+	CurrentFeed string    `json:"currentFeed,omitempty"`
+	StartedAt   string    `json:"startedAt"`
+	FinishedAt  *string   `json:"finishedAt"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Summary formats the job progress as a single chat line
+func (j CheckJob) Summary() string {
+	switch j.Status {
+	case JobRunning:
+		if j.CurrentFeed != "" {
+			return fmt.Sprintf("🔍 Checking feeds... %d/%d (current: %s)", j.Done, j.Total, j.CurrentFeed)
+		}
+		return fmt.Sprintf("🔍 Checking feeds... %d/%d", j.Done, j.Total)
+	case JobCancelled:
+		return fmt.Sprintf("🛑 Check cancelled after %d/%d feeds", j.Done, j.Total)
+	case JobFailed:
+		return fmt.Sprintf("❌ Check failed: %s", j.Error)
+	}
+
+	return fmt.Sprintf("✅ Check complete - %d feed(s) checked, %d failed", j.Done, j.Failed)
+}
+
+// CheckAction is what a check would do with a feed
+type CheckAction string
+
+const (
+	CheckActionNew        CheckAction = "new"         // A new release, subscribers are notified
+	CheckActionUnchanged  CheckAction = "unchanged"   // Latest item is the one already seen
+	CheckActionFirstCheck CheckAction = "first_check" // Latest item is stored without notifying
+	CheckActionNoMatch    CheckAction = "no_match"    // No item matches the search text
+	CheckActionWaiting    CheckAction = "waiting"     // A new episode is held for a preferred release
+	CheckActionFailed     CheckAction = "failed"
+)
+
+// CheckPreview describes what checking a feed would do, from a dry run
+type CheckPreview struct {
+	FeedID    string        `json:"feedId"`
+	FeedName  string        `json:"feedName"`
+	Action    CheckAction   `json:"action"`
+	Items     []PreviewItem `json:"items"`
+	Latest    *PreviewItem  `json:"latest,omitempty"` // The item that would be tracked
+	Notify    []string      `json:"notify"`           // Channels that would be notified
+	Error     string        `json:"error,omitempty"`
+	ErrorKind ErrorKind     `json:"errorKind,omitempty"`
+}
+
+// PreviewItem is a parsed feed item in a CheckPreview
+type PreviewItem struct {
+	Title   string `json:"title"`
+	Link    string `json:"link"`
+	Date    string `json:"date,omitempty"`
+	Matches bool   `json:"matches"` // Passes the feed's search text
+	New     bool   `json:"new"`     // Would be notified as a new release
+	ReleaseInfo
+}
+
+// ReleaseInfo is what a source knows about a release beyond its title
+type ReleaseInfo struct {
+	Number   string       `json:"number,omitempty"` // Chapter or episode number, e.g. "12.5"
+	Chapter  string       `json:"chapter,omitempty"`
+	Volume   string       `json:"volume,omitempty"`
+	Group    string       `json:"group,omitempty"`
+	Language string       `json:"language,omitempty"`
+	Torrent  *TorrentInfo `json:"torrent,omitempty"`
+}
+
+// TorrentInfo is the torrent metadata of a release from a tracker feed
+type TorrentInfo struct {
+	InfoHash string `json:"infoHash,omitempty"`
+	Magnet   string `json:"magnet,omitempty"`
+	URL      string `json:"url,omitempty"` // The .torrent file
+	Seeders  int    `json:"seeders"`
+	Size     string `json:"size,omitempty"`
+	Category string `json:"category,omitempty"`
+}
+
+// FeedPreview describes a feed URL before it is added
+type FeedPreview struct {
+	URL           string        `json:"url"` // The URL that would be stored
+	Title         string        `json:"title"`
+	ItemCount     int           `json:"itemCount"`
+	Items         []PreviewItem `json:"items"` // The latest items
+	DetectedType  FeedType      `json:"detectedType,omitempty"`
+	SearchMatches int           `json:"searchMatches"` // Items matching the search text
+	Warnings      []string      `json:"warnings"`
+	Error         string        `json:"error,omitempty"`
+	ErrorKind     ErrorKind     `json:"errorKind,omitempty"`
+
+	Candidates []DiscoveredFeed `json:"candidates,omitempty"` // Feeds found on the page, if a page URL was given
+}
+
+// DiscoveredFeed is a feed found for a page URL by autodiscovery
+type DiscoveredFeed struct {
+	URL       string `json:"url"`
+	Title     string `json:"title"`
+	Format    string `json:"format"` // rss, atom or json
+	Source    string `json:"source"` // How it was found: direct, link, anchor, pattern or guess
+	ItemCount int    `json:"itemCount"`
+}
+
+// Scope is the access level granted to an API key
+type Scope string
+
+const (
+	ScopeRead  Scope = "read"
+	ScopeAdmin Scope = "admin"
+)
+
+// APIKey is a bearer token for scripts and integrations. Only a hash of the
+// key is stored.
+type APIKey struct {
+	ID         string  `json:"id"`
+	UserID     string  `json:"userId"`
+	Name       string  `json:"name"`
+	Scope      Scope   `json:"scope"`
+	Prefix     string  `json:"prefix"` // First characters of the key, to tell keys apart
+	Hash       string  `json:"hash,omitempty"`
+	CreatedAt  string  `json:"createdAt"`
+	LastUsedAt *string `json:"lastUsedAt"`
 }
 
 // GotifyMessage represents a message to send to Gotify
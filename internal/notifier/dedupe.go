@@ -0,0 +1,127 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"shinkan-rebirth/internal/events"
+)
+
+// dedupe remembers the releases notified per series, so a chapter carried
+// by several feeds of one series is only notified once within the window.
+// Like the queue, the file is read before every change, so one-off CLI
+// checks and the daemon share what was notified.
+type dedupe struct {
+	mu       sync.Mutex
+	window   time.Duration
+	path     string
+	releases []seenRelease // Used when there is no file
+}
+
+type seenRelease struct {
+	OwnerID  string    `json:"ownerId"`
+	SeriesID string    `json:"seriesId"`
+	Number   string    `json:"number"`
+	First    time.Time `json:"first"`
+	Sources  []string  `json:"sources"` // Names of the feeds that carried the release, first one notified
+}
+
+func newDedupe(window time.Duration, path string) *dedupe {
+	return &dedupe{window: window, path: path}
+}
+
+// seen records a release and reports whether another feed of the same
+// series already carried it within the window, with all sources so far.
+// Releases of feeds without a series or number are never duplicates.
+func (d *dedupe) seen(release events.ReleaseDetected, now time.Time) (bool, []string, error) {
+	feed := release.Feed
+	if d.window <= 0 || feed.SeriesID == "" || release.Info.Number == "" {
+		return false, nil, nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	releases, err := d.load()
+	if err != nil {
+		return false, nil, err
+	}
+
+	kept := releases[:0]
+	for _, r := range releases {
+		if now.Sub(r.First) < d.window {
+			kept = append(kept, r)
+		}
+	}
+	releases = kept
+
+	for i := range releases {
+		r := &releases[i]
+		if r.OwnerID != feed.OwnerID || r.SeriesID != feed.SeriesID || r.Number != release.Info.Number {
+			continue
+		}
+		for _, source := range r.Sources {
+			if source == feed.Name {
+				return true, r.Sources, d.save(releases)
+			}
+		}
+		r.Sources = append(r.Sources, feed.Name)
+		return true, r.Sources, d.save(releases)
+	}
+
+	releases = append(releases, seenRelease{
+		OwnerID:  feed.OwnerID,
+		SeriesID: feed.SeriesID,
+		Number:   release.Info.Number,
+		First:    now,
+		Sources:  []string{feed.Name},
+	})
+	return false, nil, d.save(releases)
+}
+
+// load returns the remembered releases
+func (d *dedupe) load() ([]seenRelease, error) {
+	if d.path == "" {
+		return d.releases, nil
+	}
+
+	releases := []seenRelease{}
+	raw, err := os.ReadFile(d.path)
+	if os.IsNotExist(err) {
+		return releases, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &releases); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", d.path, err)
+	}
+	return releases, nil
+}
+
+// save replaces the remembered releases
+func (d *dedupe) save(releases []seenRelease) error {
+	if d.path == "" {
+		d.releases = releases
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(d.path), 0755); err != nil {
+		return err
+	}
+	raw, err := json.MarshalIndent(releases, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash can't leave half a file
+	tmp := d.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, d.path)
+}
@@ -0,0 +1,152 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+
+	"shinkan-rebirth/internal/models"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxSummaryLength keeps batched Discord summaries under the embed
+// description limit
+const maxSummaryLength = 3900
+
+// message is one release notification, before it is formatted for a channel
+type message struct {
+	Title    string `json:"title"`
+	Name     string `json:"name"`
+	Chapter  string `json:"chapter"`
+	Link     string `json:"link"`
+	Anilist  string `json:"anilist,omitempty"`
+	Cover    string `json:"cover,omitempty"`
+	Priority int    `json:"priority"`
+	Color    int    `json:"color"`
+
+	Sources []string `json:"sources,omitempty"` // Feeds of the series that carried the release while it was held
+}
+
+// newMessage is the notification of a feed's release, without its title
+// and priority
+func newMessage(feed models.Feed, chapter, link string) message {
+	msg := message{Name: feed.Name, Chapter: chapter, Link: link}
+	if feed.AnilistUrl != nil {
+		msg.Anilist = *feed.AnilistUrl
+	}
+	if feed.Cover != nil {
+		msg.Cover = *feed.Cover
+	}
+
+	if feed.Type == models.FeedTypeAnime {
+		msg.Color = 0x89b4fa // Blue for anime
+	} else {
+		msg.Color = 0xa6e3a1 // Green for manga
+	}
+	return msg
+}
+
+// gotify formats the message for Gotify, which doesn't support images
+func (m message) gotify() models.GotifyMessage {
+	text := fmt.Sprintf("**%s**\n%s", m.Name, m.Chapter) + m.sources()
+	if m.Anilist != "" {
+		text += fmt.Sprintf("\n\n📺 AniList: %s", m.Anilist)
+	}
+	text += fmt.Sprintf("\n\n🔗 Link: %s", m.Link)
+
+	return models.GotifyMessage{
+		Title:    m.Title,
+		Message:  text,
+		Priority: m.Priority,
+		Extras: map[string]interface{}{
+			"client::display": map[string]interface{}{
+				"contentType": "text/markdown",
+			},
+		},
+	}
+}
+
+// embed formats the message for Discord, with the cover as thumbnail
+func (m message) embed() *discordgo.MessageEmbed {
+	description := fmt.Sprintf("**%s**\n%s", m.Name, m.Chapter) + m.sources()
+	if m.Anilist != "" {
+		description += fmt.Sprintf("\n\n[📺 View on AniList](%s)", m.Anilist)
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       m.Title,
+		Description: description,
+		URL:         m.Link,
+		Color:       m.Color,
+	}
+	if m.Cover != "" {
+		embed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: m.Cover}
+	}
+	return embed
+}
+
+// headline is the first line of a release text, leaving out torrent
+// details in summaries
+func (m message) headline() string {
+	line, _, _ := strings.Cut(m.Chapter, "\n")
+	return line
+}
+
+// sources is a line naming the feeds that carried the release, if more than
+// one did
+func (m message) sources() string {
+	if len(m.Sources) < 2 {
+		return ""
+	}
+	return "\n📡 Sources: " + strings.Join(m.Sources, ", ")
+}
+
+func summaryTitle(items []heldNotification) string {
+	return fmt.Sprintf("📬 %d new releases", len(items))
+}
+
+// summaryGotify collects held notifications into one Gotify message, as
+// urgent as the most urgent of them
+func summaryGotify(items []heldNotification) models.GotifyMessage {
+	lines := make([]string, 0, len(items))
+	priority := 0
+	for _, item := range items {
+		m := item.Message
+		lines = append(lines, fmt.Sprintf("**%s**\n%s%s\n🔗 %s", m.Name, m.headline(), m.sources(), m.Link))
+		if m.Priority > priority {
+			priority = m.Priority
+		}
+	}
+
+	return models.GotifyMessage{
+		Title:    summaryTitle(items),
+		Message:  strings.Join(lines, "\n\n"),
+		Priority: priority,
+		Extras: map[string]interface{}{
+			"client::display": map[string]interface{}{
+				"contentType": "text/markdown",
+			},
+		},
+	}
+}
+
+// summaryEmbed collects held notifications into one Discord embed, one
+// line per release
+func summaryEmbed(items []heldNotification) *discordgo.MessageEmbed {
+	var description strings.Builder
+	for i, item := range items {
+		m := item.Message
+		line := fmt.Sprintf("**%s** · [%s](%s)%s\n", m.Name, m.headline(), m.Link, m.sources())
+		if description.Len()+len(line) > maxSummaryLength {
+			fmt.Fprintf(&description, "…and %d more", len(items)-i)
+			break
+		}
+		description.WriteString(line)
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       summaryTitle(items),
+		Description: strings.TrimSuffix(description.String(), "\n"),
+		Color:       items[0].Message.Color,
+	}
+}
@@ -2,32 +2,60 @@ package notifier
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
+	"shinkan-rebirth/internal/events"
 	"shinkan-rebirth/internal/models"
 
 	"github.com/bwmarrin/discordgo"
 )
 
+// Notification channels, as reported in events.NotificationSent
+const (
+	ChannelGotify  = "gotify"
+	ChannelDiscord = "discord"
+)
+
 type Notifier struct {
 	gotifyServer     string
 	gotifyToken      string
 	discordSession   *discordgo.Session
 	discordChannelID string
 	httpClient       *http.Client
+	timeout          time.Duration
+	events           events.Publisher
+	targets          TargetResolver
+	dedupe           *dedupe
+	schedule         Schedule
+	queue            *queue
 	commandHandlers  map[string]func(*discordgo.Session, *discordgo.InteractionCreate)
 }
 
-func New(gotifyServer, gotifyToken, discordToken, discordChannelID string) *Notifier {
+// TargetResolver looks up a user's own notification targets
+type TargetResolver func(userID string) models.NotificationTargets
+
+// New creates a notifier. Releases of one series carried by several feeds
+// are notified once per dedupeWindow, remembered in dedupeFile if set, and
+// the schedule holds them back during quiet hours and batch windows.
+func New(gotifyServer, gotifyToken, discordToken, discordChannelID string, timeout, dedupeWindow time.Duration, dedupeFile string, schedule Schedule, publisher events.Publisher, targets TargetResolver) *Notifier {
 	n := &Notifier{
 		gotifyServer:     strings.TrimSuffix(gotifyServer, "/"),
 		gotifyToken:      gotifyToken,
 		discordChannelID: discordChannelID,
-		httpClient:       &http.Client{},
+		httpClient:       &http.Client{Timeout: timeout},
+		timeout:          timeout,
+		events:           publisher,
+		targets:          targets,
+		dedupe:           newDedupe(dedupeWindow, dedupeFile),
+		schedule:         schedule,
+		queue:            newQueue(schedule.QueueFile),
 		commandHandlers:  make(map[string]func(*discordgo.Session, *discordgo.InteractionCreate)),
 	}
 
@@ -37,6 +65,7 @@ func New(gotifyServer, gotifyToken, discordToken, discordChannelID string) *Noti
 		if err != nil {
 			log.Printf("⚠️ Failed to create Discord session: %v\n", err)
 		} else {
+			session.Client.Timeout = timeout
 			session.Identify.Intents = discordgo.IntentsGuilds
 			err = session.Open()
 			if err != nil {
@@ -44,7 +73,7 @@ func New(gotifyServer, gotifyToken, discordToken, discordChannelID string) *Noti
 			} else {
 				n.discordSession = session
 				log.Println("✅ Discord bot connected")
-				
+
 				// Set bot presence
 				session.UpdateStatusComplex(discordgo.UpdateStatusData{
 					Activities: []*discordgo.Activity{{
@@ -60,111 +89,237 @@ func New(gotifyServer, gotifyToken, discordToken, discordChannelID string) *Noti
 	return n
 }
 
-func (n *Notifier) SendNotification(feedName, chapter, link, feedType string, anilistUrl *string, cover *string) error {
-	var title, message string
-	var priority int
-	var color int
+// HandleEvent sends a notification for every detected release
+func (n *Notifier) HandleEvent(ctx context.Context, event events.Event) {
+	release, ok := event.(events.ReleaseDetected)
+	if !ok {
+		return
+	}
 
-	if feedType == string(models.FeedTypeAnime) {
-		title = "🎬 New Anime Episode!"
-		message = fmt.Sprintf("**%s**\n%s", feedName, chapter)
-		priority = 7
-		color = 0x89b4fa // Blue for anime
-	} else {
-		title = "📖 New Manga Chapter!"
-		message = fmt.Sprintf("**%s**\n%s", feedName, chapter)
-		priority = 5
-		color = 0xa6e3a1 // Green for manga
+	if release.Series != nil && release.Series.Status == models.SeriesDropped {
+		log.Printf("🔕 [%s] Not notifying %s, %s is dropped\n", release.Feed.Name, release.Title, release.Series.Title)
+		return
 	}
 
-	if anilistUrl != nil && *anilistUrl != "" {
-		message += fmt.Sprintf("\n\n📺 AniList: %s", *anilistUrl)
+	// Notifying twice beats missing a release, so a dedupe error only logs
+	duplicate, sources, err := n.dedupe.seen(release, time.Now())
+	if err != nil {
+		log.Printf("⚠️ [%s] Failed to look up notified releases: %v\n", release.Feed.Name, err)
+	}
+	if duplicate {
+		log.Printf("🔁 [%s] %s was already notified for this series (carried by %s)\n",
+			release.Feed.Name, release.Title, strings.Join(sources, ", "))
+		n.events.Publish(ctx, events.ReleaseDuplicate{Feed: release.Feed, Title: release.Title, Sources: sources})
+		n.noteSources(release, sources)
+		return
 	}
 
-	message += fmt.Sprintf("\n\n🔗 Link: %s", link)
+	feed := WithSeries(release.Feed, release.Series)
+	if err := n.notify(ctx, feed, release.Info.Number, releaseText(release), release.Link); err != nil {
+		log.Printf("⚠️ [%s] Failed to send notification: %v\n", release.Feed.Name, err)
+	}
+}
 
-	// Send to Gotify if configured (Gotify doesn't support images)
-	if n.gotifyServer != "" && n.gotifyToken != "" {
-		gotifyMsg := models.GotifyMessage{
-			Title:    title,
-			Message:  message,
-			Priority: priority,
-			Extras: map[string]interface{}{
-				"client::display": map[string]interface{}{
-					"contentType": "text/markdown",
-				},
-			},
+// WithSeries presents a feed under its series' title, AniList link and
+// cover, so every source of a series notifies alike
+func WithSeries(feed models.Feed, series *models.Series) models.Feed {
+	if series == nil {
+		return feed
+	}
+	if series.Title != "" {
+		feed.Name = series.Title
+	}
+	if url := series.AnilistURL(); url != "" {
+		feed.AnilistUrl = &url
+	}
+	if series.Cover != "" {
+		cover := series.Cover
+		feed.Cover = &cover
+	}
+	return feed
+}
+
+// releaseText is the release title with the volume and group where the
+// source knows them, e.g. "Chapter 12 (Vol. 3) [Group]", the feed it came
+// from if the series is named otherwise, and the torrent details of
+// torrent releases
+func releaseText(release events.ReleaseDetected) string {
+	text := release.Title
+	if release.Info.Volume != "" {
+		text += fmt.Sprintf(" (Vol. %s)", release.Info.Volume)
+	}
+	if release.Info.Group != "" {
+		text += fmt.Sprintf(" [%s]", release.Info.Group)
+	}
+	if release.Series != nil && release.Series.Title != release.Feed.Name {
+		text += fmt.Sprintf("\n📡 via %s", release.Feed.Name)
+	}
+
+	// Torrent releases get their size, seeders and magnet link
+	if torrent := release.Info.Torrent; torrent != nil {
+		if torrent.Size != "" {
+			text += fmt.Sprintf("\n💾 %s · 🌱 %d seeders", torrent.Size, torrent.Seeders)
 		}
-		if err := n.sendToGotify(gotifyMsg); err != nil {
-			log.Printf("⚠️ Gotify notification failed: %v\n", err)
+		if torrent.Magnet != "" {
+			text += fmt.Sprintf("\n\n🧲 Magnet: %s", torrent.Magnet)
 		}
 	}
+	return text
+}
+
+// targetFor returns where to deliver a user's notifications: their own
+// targets where set, the global configuration otherwise
+func (n *Notifier) targetFor(userID string) models.NotificationTargets {
+	target := models.NotificationTargets{
+		GotifyServer:     n.gotifyServer,
+		GotifyToken:      n.gotifyToken,
+		DiscordChannelID: n.discordChannelID,
+	}
 
-	// Send to Discord if configured (with cover image support)
-	if n.discordSession != nil && n.discordChannelID != "" {
-		if err := n.sendToDiscord(title, feedName, chapter, link, anilistUrl, cover, color); err != nil {
-			log.Printf("⚠️ Discord notification failed: %v\n", err)
+	if n.targets == nil {
+		return target
+	}
+
+	// Users without a server of their own have an application token for
+	// the global one
+	user := n.targets(userID)
+	if user.GotifyToken != "" {
+		if user.GotifyServer != "" {
+			target.GotifyServer = strings.TrimSuffix(user.GotifyServer, "/")
 		}
+		target.GotifyToken = user.GotifyToken
 	}
+	if user.DiscordChannelID != "" {
+		target.DiscordChannelID = user.DiscordChannelID
+	}
+	return target
+}
 
-	return nil
+// Channels lists the channels a user's notifications would be sent to
+func (n *Notifier) Channels(userID string) []string {
+	target := n.targetFor(userID)
+	channels := make([]string, 0, 2)
+
+	if target.GotifyServer != "" && target.GotifyToken != "" {
+		channels = append(channels, ChannelGotify)
+	}
+	if n.discordSession != nil && target.DiscordChannelID != "" {
+		channels = append(channels, ChannelDiscord)
+	}
+	return channels
 }
 
-func (n *Notifier) SendTestNotification(feedName, chapter, link, feedType string, anilistUrl *string, cover *string) error {
-	var title, message string
-	var color int
+// publishResult reports the outcome of a delivery on one channel
+func (n *Notifier) publishResult(ctx context.Context, feed models.Feed, channel string, test bool, err error) {
+	event := events.NotificationSent{Feed: feed, Channel: channel, Test: test}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	n.events.Publish(ctx, event)
+}
+
+// SendNotification notifies a release on every channel of the feed's owner,
+// or holds it on channels that are in their quiet hours or batch window. The
+// error names every channel that failed, held messages are not failures.
+func (n *Notifier) SendNotification(ctx context.Context, feed models.Feed, chapter, link string) error {
+	return n.notify(ctx, feed, "", chapter, link)
+}
 
-	if feedType == string(models.FeedTypeAnime) {
-		title = "🧪 TEST: Anime Notification"
-		color = 0x89b4fa
+// notify is SendNotification for a release number, which held messages
+// keep so later sources of the release can be noted on them
+func (n *Notifier) notify(ctx context.Context, feed models.Feed, number, chapter, link string) error {
+	target := n.targetFor(feed.OwnerID)
+	msg := newMessage(feed, chapter, link)
+	if feed.Type == models.FeedTypeAnime {
+		msg.Title = "🎬 New Anime Episode!"
+		msg.Priority = 7
 	} else {
-		title = "🧪 TEST: Manga Notification"
-		color = 0xa6e3a1
+		msg.Title = "📖 New Manga Chapter!"
+		msg.Priority = 5
 	}
 
-	message = fmt.Sprintf("**%s**\n%s", feedName, chapter)
+	now := time.Now()
+	var failed []string
+	for _, channel := range n.Channels(feed.OwnerID) {
+		if held, quietUntil := n.schedule.holds(channel, now); held {
+			n.hold(feed, number, channel, msg, now, quietUntil)
+			continue
+		}
 
-	if anilistUrl != nil && *anilistUrl != "" {
-		message += fmt.Sprintf("\n\n📺 AniList: %s", *anilistUrl)
+		err := n.send(ctx, channel, target, msg.gotify(), msg.embed())
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", channelNames[channel], err))
+		}
+		n.publishResult(ctx, feed, channel, false, err)
 	}
 
-	message += fmt.Sprintf("\n\n🔗 Link: %s", link)
+	if len(failed) > 0 {
+		return errors.New(strings.Join(failed, "; "))
+	}
+	return nil
+}
 
-	// Send to Gotify if configured (Gotify doesn't support images)
-	if n.gotifyServer != "" && n.gotifyToken != "" {
-		gotifyMsg := models.GotifyMessage{
-			Title:    title,
-			Message:  message,
-			Priority: 3,
-			Extras: map[string]interface{}{
-				"client::display": map[string]interface{}{
-					"contentType": "text/markdown",
-				},
-			},
-		}
-		if err := n.sendToGotify(gotifyMsg); err != nil {
-			log.Printf("⚠️ Gotify test notification failed: %v\n", err)
-		}
+// SendTestNotification sends a test notification right away, quiet hours
+// or not
+func (n *Notifier) SendTestNotification(ctx context.Context, feed models.Feed, chapter, link string) error {
+	target := n.targetFor(feed.OwnerID)
+	msg := newMessage(feed, chapter, link)
+	msg.Priority = 3
+	if feed.Type == models.FeedTypeAnime {
+		msg.Title = "🧪 TEST: Anime Notification"
+	} else {
+		msg.Title = "🧪 TEST: Manga Notification"
 	}
 
-	// Send to Discord if configured (with cover image support)
-	if n.discordSession != nil && n.discordChannelID != "" {
-		if err := n.sendToDiscord(title, feedName, chapter, link, anilistUrl, cover, color); err != nil {
-			log.Printf("⚠️ Discord test notification failed: %v\n", err)
+	for _, channel := range n.Channels(feed.OwnerID) {
+		err := n.send(ctx, channel, target, msg.gotify(), msg.embed())
+		if err != nil {
+			log.Printf("⚠️ %s test notification failed: %v\n", channelNames[channel], err)
 		}
+		n.publishResult(ctx, feed, channel, true, err)
 	}
 
 	return nil
 }
 
-func (n *Notifier) sendToGotify(msg models.GotifyMessage) error {
+// errNotConfigured is returned for channels the user has no target for
+var errNotConfigured = errors.New("is not configured")
+
+var channelNames = map[string]string{
+	ChannelGotify:  "Gotify",
+	ChannelDiscord: "Discord",
+}
+
+// send delivers a message on one channel, as a Gotify message or a Discord
+// embed
+func (n *Notifier) send(ctx context.Context, channel string, target models.NotificationTargets, gotify models.GotifyMessage, embed *discordgo.MessageEmbed) error {
+	switch channel {
+	case ChannelGotify:
+		if target.GotifyServer == "" || target.GotifyToken == "" {
+			return fmt.Errorf("gotify %w", errNotConfigured)
+		}
+		return n.sendToGotify(ctx, target, gotify)
+	case ChannelDiscord:
+		if n.discordSession == nil || target.DiscordChannelID == "" {
+			return fmt.Errorf("discord %w", errNotConfigured)
+		}
+		return n.sendToDiscord(ctx, target.DiscordChannelID, embed)
+	default:
+		return fmt.Errorf("unknown channel %q", channel)
+	}
+}
+
+func (n *Notifier) sendToGotify(ctx context.Context, target models.NotificationTargets, msg models.GotifyMessage) error {
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+
 	jsonData, err := json.Marshal(msg)
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	url := fmt.Sprintf("%s/message?token=%s", n.gotifyServer, n.gotifyToken)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	url := fmt.Sprintf("%s/message?token=%s", target.GotifyServer, target.GotifyToken)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -184,28 +339,11 @@ func (n *Notifier) sendToGotify(msg models.GotifyMessage) error {
 	return nil
 }
 
-func (n *Notifier) sendToDiscord(title, feedName, chapter, link string, anilistUrl *string, cover *string, color int) error {
-	description := fmt.Sprintf("**%s**\n%s", feedName, chapter)
-	
-	if anilistUrl != nil && *anilistUrl != "" {
-		description += fmt.Sprintf("\n\n[📺 View on AniList](%s)", *anilistUrl)
-	}
-
-	embed := &discordgo.MessageEmbed{
-		Title:       title,
-		Description: description,
-		URL:         link,
-		Color:       color,
-	}
-	
-	// Add thumbnail if cover image is provided
-	if cover != nil && *cover != "" {
-		embed.Thumbnail = &discordgo.MessageEmbedThumbnail{
-			URL: *cover,
-		}
-	}
+func (n *Notifier) sendToDiscord(ctx context.Context, channelID string, embed *discordgo.MessageEmbed) error {
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
 
-	_, err := n.discordSession.ChannelMessageSendEmbed(n.discordChannelID, embed)
+	_, err := n.discordSession.ChannelMessageSendEmbed(channelID, embed, discordgo.WithContext(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to send Discord message: %w", err)
 	}
@@ -213,7 +351,15 @@ func (n *Notifier) sendToDiscord(title, feedName, chapter, link string, anilistU
 	return nil
 }
 
-func (n *Notifier) RegisterCommands(checkCallback func(), quoteCallback func() string) error {
+// interactionTimeout is how long Discord accepts edits of a slash command
+// response
+const interactionTimeout = 15 * time.Minute
+
+// RegisterCommands sets up the slash commands. checkCallback starts (or joins)
+// a check and returns a channel of progress messages that is closed once the
+// check finishes or ctx is done; the /check response is edited as each
+// message arrives.
+func (n *Notifier) RegisterCommands(checkCallback func(ctx context.Context) <-chan string, quoteCallback func() string) error {
 	if n.discordSession == nil {
 		return nil
 	}
@@ -246,13 +392,27 @@ func (n *Notifier) RegisterCommands(checkCallback func(), quoteCallback func() s
 	n.discordSession.AddHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		switch i.ApplicationCommandData().Name {
 		case "check":
+			ctx, cancel := context.WithTimeout(context.Background(), interactionTimeout)
+			progress := checkCallback(ctx)
+			content, ok := <-progress
+			if !ok {
+				content = "🔍 Starting manual check..."
+			}
 			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 				Type: discordgo.InteractionResponseChannelMessageWithSource,
 				Data: &discordgo.InteractionResponseData{
-					Content: "🔍 Starting manual check...",
+					Content: content,
 				},
 			})
-			go checkCallback()
+			go func() {
+				defer cancel()
+				for content := range progress {
+					content := content
+					if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content}); err != nil {
+						log.Printf("⚠️ Failed to update /check progress: %v\n", err)
+					}
+				}
+			}()
 
 		case "quote":
 			quote := quoteCallback()
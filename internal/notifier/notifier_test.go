@@ -0,0 +1,297 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"shinkan-rebirth/internal/events"
+	"shinkan-rebirth/internal/models"
+)
+
+// recorder is a fake publisher keeping every event
+type recorder struct {
+	mu     sync.Mutex
+	events []events.Event
+}
+
+func (r *recorder) Publish(ctx context.Context, event events.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) named(name string) []events.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found []events.Event
+	for _, event := range r.events {
+		if event.EventName() == name {
+			found = append(found, event)
+		}
+	}
+	return found
+}
+
+// fakeGotify records the messages posted to it and answers with status
+type fakeGotify struct {
+	*httptest.Server
+	mu       sync.Mutex
+	status   int
+	messages []models.GotifyMessage
+}
+
+func newFakeGotify(t *testing.T) *fakeGotify {
+	g := &fakeGotify{status: http.StatusOK}
+	g.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/message" || r.URL.Query().Get("token") != "token" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		var msg models.GotifyMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("invalid message: %v", err)
+		}
+
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if g.status == http.StatusOK {
+			g.messages = append(g.messages, msg)
+		}
+		w.WriteHeader(g.status)
+	}))
+	t.Cleanup(g.Close)
+	return g
+}
+
+func (g *fakeGotify) received() []models.GotifyMessage {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]models.GotifyMessage(nil), g.messages...)
+}
+
+func (g *fakeGotify) setStatus(status int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.status = status
+}
+
+func newTestNotifier(t *testing.T, gotify *fakeGotify, schedule Schedule) (*Notifier, *recorder) {
+	publisher := &recorder{}
+	n := New(gotify.URL, "token", "", "", 5*time.Second, time.Hour, "", schedule, publisher, nil)
+	t.Cleanup(n.Close)
+	return n, publisher
+}
+
+func release(feedID, feedName, number string) events.ReleaseDetected {
+	return events.ReleaseDetected{
+		Feed:  models.Feed{ID: feedID, Name: feedName, OwnerID: models.DefaultUserID, SeriesID: "series", Type: models.FeedTypeManga},
+		Title: "Chapter " + number,
+		Link:  "https://example.com/" + feedID + "/" + number,
+		Info:  models.ReleaseInfo{Number: number},
+	}
+}
+
+func TestHandleEventNotifiesAndPublishesResult(t *testing.T) {
+	gotify := newFakeGotify(t)
+	n, publisher := newTestNotifier(t, gotify, Schedule{})
+
+	n.HandleEvent(context.Background(), release("a", "Official", "12"))
+
+	messages := gotify.received()
+	if len(messages) != 1 {
+		t.Fatalf("gotify got %d messages, want 1", len(messages))
+	}
+	if messages[0].Title != "📖 New Manga Chapter!" || messages[0].Priority != 5 {
+		t.Errorf("message = %+v", messages[0])
+	}
+
+	sent := publisher.named("notification-sent")
+	if len(sent) != 1 {
+		t.Fatalf("published %d notification-sent events, want 1", len(sent))
+	}
+	if e := sent[0].(events.NotificationSent); e.Channel != ChannelGotify || e.Error != "" || e.Test {
+		t.Errorf("notification-sent = %+v", e)
+	}
+}
+
+func TestSendNotificationReturnsDeliveryErrors(t *testing.T) {
+	gotify := newFakeGotify(t)
+	gotify.setStatus(http.StatusInternalServerError)
+	n, publisher := newTestNotifier(t, gotify, Schedule{})
+
+	r := release("a", "Official", "12")
+	err := n.SendNotification(context.Background(), r.Feed, r.Title, r.Link)
+	if err == nil || !strings.HasPrefix(err.Error(), "Gotify: ") {
+		t.Fatalf("err = %v, want the Gotify failure", err)
+	}
+
+	sent := publisher.named("notification-sent")
+	if len(sent) != 1 || sent[0].(events.NotificationSent).Error == "" {
+		t.Errorf("notification-sent = %+v, want the failure reported", sent)
+	}
+}
+
+func TestHandleEventSkipsDuplicates(t *testing.T) {
+	gotify := newFakeGotify(t)
+	n, publisher := newTestNotifier(t, gotify, Schedule{})
+	ctx := context.Background()
+
+	n.HandleEvent(ctx, release("a", "Official", "12"))
+	n.HandleEvent(ctx, release("b", "Scans", "12"))
+
+	if got := len(gotify.received()); got != 1 {
+		t.Errorf("gotify got %d messages, want 1", got)
+	}
+	duplicates := publisher.named("release-duplicate")
+	if len(duplicates) != 1 {
+		t.Fatalf("published %d release-duplicate events, want 1", len(duplicates))
+	}
+	if e := duplicates[0].(events.ReleaseDuplicate); len(e.Sources) != 2 || e.Sources[0] != "Official" {
+		t.Errorf("duplicate sources = %v, want [Official Scans]", e.Sources)
+	}
+}
+
+func TestDedupeSurvivesRestarts(t *testing.T) {
+	gotify := newFakeGotify(t)
+	file := filepath.Join(t.TempDir(), "dedupe.json")
+	ctx := context.Background()
+
+	// A notifier per check, like one-off CLI checks
+	for _, r := range []events.ReleaseDetected{release("a", "Official", "12"), release("b", "Scans", "12")} {
+		n := New(gotify.URL, "token", "", "", 5*time.Second, time.Hour, file, Schedule{}, &recorder{}, nil)
+		n.HandleEvent(ctx, r)
+		n.Close()
+	}
+
+	if got := len(gotify.received()); got != 1 {
+		t.Errorf("gotify got %d messages, want 1", got)
+	}
+}
+
+func TestHeldNotificationListsLaterSources(t *testing.T) {
+	gotify := newFakeGotify(t)
+	n, _ := newTestNotifier(t, gotify, Schedule{Quiet: map[string]*QuietHours{ChannelGotify: quietNow(t)}})
+	ctx := context.Background()
+
+	n.HandleEvent(ctx, release("a", "Official", "12"))
+	n.HandleEvent(ctx, release("b", "Scans", "12"))
+	n.schedule.Quiet = nil
+	n.Flush(ctx)
+
+	messages := gotify.received()
+	if len(messages) != 1 {
+		t.Fatalf("gotify got %d messages, want 1", len(messages))
+	}
+	if !strings.Contains(messages[0].Message, "📡 Sources: Official, Scans") {
+		t.Errorf("message = %q, want both sources listed", messages[0].Message)
+	}
+}
+
+func TestHandleEventSkipsDroppedSeries(t *testing.T) {
+	gotify := newFakeGotify(t)
+	n, publisher := newTestNotifier(t, gotify, Schedule{})
+
+	dropped := release("a", "Official", "13")
+	dropped.Series = &models.Series{ID: "series", Title: "Series", Status: models.SeriesDropped}
+	n.HandleEvent(context.Background(), dropped)
+
+	if got := len(gotify.received()); got != 0 {
+		t.Errorf("gotify got %d messages for a dropped series", got)
+	}
+	if got := len(publisher.named("notification-sent")); got != 0 {
+		t.Errorf("published %d notification-sent events for a dropped series", got)
+	}
+}
+
+// quietNow is quiet hours from an hour ago to an hour from now
+func quietNow(t *testing.T) *QuietHours {
+	now := time.Now().UTC()
+	quiet, err := ParseQuietHours(now.Add(-time.Hour).Format("15:04")+"-"+now.Add(time.Hour).Format("15:04"), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return quiet
+}
+
+func TestQuietHoursHoldAndSummarize(t *testing.T) {
+	gotify := newFakeGotify(t)
+	n, publisher := newTestNotifier(t, gotify, Schedule{
+		Quiet:     map[string]*QuietHours{ChannelGotify: quietNow(t)},
+		QueueFile: filepath.Join(t.TempDir(), "queue.json"),
+	})
+	ctx := context.Background()
+
+	n.HandleEvent(ctx, release("a", "Alpha", "1"))
+	n.HandleEvent(ctx, release("b", "Beta", "2"))
+	n.Flush(ctx)
+	if got := len(gotify.received()); got != 0 {
+		t.Fatalf("gotify got %d messages during quiet hours", got)
+	}
+
+	// Quiet hours are over, but Gotify is down: nothing may be lost
+	n.schedule.Quiet = nil
+	gotify.setStatus(http.StatusInternalServerError)
+	n.Flush(ctx)
+	if got := len(publisher.named("notification-sent")); got != 0 {
+		t.Fatalf("published %d results for a failed delivery, want 0", got)
+	}
+
+	gotify.setStatus(http.StatusOK)
+	n.Flush(ctx)
+	messages := gotify.received()
+	if len(messages) != 1 {
+		t.Fatalf("gotify got %d messages, want one summary", len(messages))
+	}
+	if messages[0].Title != "📬 2 new releases" {
+		t.Errorf("summary title = %q", messages[0].Title)
+	}
+	if got := len(publisher.named("notification-sent")); got != 2 {
+		t.Errorf("published %d notification-sent events, want one per release", got)
+	}
+
+	n.Flush(ctx)
+	if got := len(gotify.received()); got != 1 {
+		t.Errorf("gotify got %d messages after the queue was emptied, want 1", got)
+	}
+}
+
+func TestParseQuietHours(t *testing.T) {
+	quiet, err := ParseQuietHours("23:00-07:00", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(hour, minute int) time.Time { return time.Date(2026, 1, 1, hour, minute, 0, 0, time.UTC) }
+
+	for _, tc := range []struct {
+		t      time.Time
+		active bool
+	}{
+		{at(22, 59), false},
+		{at(23, 0), true},
+		{at(3, 0), true},
+		{at(6, 59), true},
+		{at(7, 0), false},
+	} {
+		if got := quiet.Active(tc.t); got != tc.active {
+			t.Errorf("Active(%s) = %v, want %v", tc.t.Format("15:04"), got, tc.active)
+		}
+	}
+	if end := quiet.End(at(23, 30)); !end.Equal(time.Date(2026, 1, 2, 7, 0, 0, 0, time.UTC)) {
+		t.Errorf("End(23:30) = %s, want 07:00 the next day", end)
+	}
+
+	if quiet, err := ParseQuietHours("off", time.UTC); quiet != nil || err != nil {
+		t.Errorf(`ParseQuietHours("off") = %v, %v, want none`, quiet, err)
+	}
+	for _, spec := range []string{"23:00", "25:00-07:00", "07:00-07:00"} {
+		if _, err := ParseQuietHours(spec, time.UTC); err == nil {
+			t.Errorf("ParseQuietHours(%q) accepted", spec)
+		}
+	}
+}
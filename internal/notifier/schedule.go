@@ -0,0 +1,315 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"shinkan-rebirth/internal/events"
+	"shinkan-rebirth/internal/models"
+)
+
+// QuietHours is a daily time range, e.g. 23:00-07:00, during which release
+// notifications are held back
+type QuietHours struct {
+	start, end int // Minutes since midnight
+	loc        *time.Location
+}
+
+// ParseQuietHours reads a range like "23:00-07:00" in the given time zone.
+// An empty spec or "off" means no quiet hours and returns nil.
+func ParseQuietHours(spec string, loc *time.Location) (*QuietHours, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" || strings.EqualFold(spec, "off") {
+		return nil, nil
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+
+	from, to, ok := strings.Cut(spec, "-")
+	if !ok {
+		return nil, fmt.Errorf("%q must look like 23:00-07:00", spec)
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", spec, err)
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", spec, err)
+	}
+	if start == end {
+		return nil, fmt.Errorf("%q starts and ends at the same time", spec)
+	}
+	return &QuietHours{start: start, end: end, loc: loc}, nil
+}
+
+func parseClock(raw string) (int, error) {
+	clock, err := time.Parse("15:04", strings.TrimSpace(raw))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time like 07:00", strings.TrimSpace(raw))
+	}
+	return clock.Hour()*60 + clock.Minute(), nil
+}
+
+// Active reports whether t falls within the quiet hours
+func (q *QuietHours) Active(t time.Time) bool {
+	if q == nil {
+		return false
+	}
+	local := t.In(q.loc)
+	minute := local.Hour()*60 + local.Minute()
+	if q.start < q.end {
+		return minute >= q.start && minute < q.end
+	}
+	// Overnight, e.g. 23:00-07:00
+	return minute >= q.start || minute < q.end
+}
+
+// End returns when the quiet hours around t are over
+func (q *QuietHours) End(t time.Time) time.Time {
+	local := t.In(q.loc)
+	end := time.Date(local.Year(), local.Month(), local.Day(), q.end/60, q.end%60, 0, 0, q.loc)
+	if !end.After(local) {
+		end = time.Date(local.Year(), local.Month(), local.Day()+1, q.end/60, q.end%60, 0, 0, q.loc)
+	}
+	return end
+}
+
+func (q *QuietHours) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d %s", q.start/60, q.start%60, q.end/60, q.end%60, q.loc)
+}
+
+// Schedule decides when release notifications go out: channels in their
+// quiet hours hold them until the quiet hours end, and a batch window
+// collects the releases of that long into one message.
+type Schedule struct {
+	Quiet       map[string]*QuietHours // Per channel, nil entries have none
+	BatchWindow time.Duration          // 0 sends releases right away outside quiet hours
+	QueueFile   string                 // Where held notifications survive restarts, empty keeps them in memory
+}
+
+// holds reports whether a notification on a channel is held back at now,
+// and until when if the channel is in its quiet hours
+func (s Schedule) holds(channel string, now time.Time) (bool, time.Time) {
+	if quiet := s.Quiet[channel]; quiet.Active(now) {
+		return true, quiet.End(now)
+	}
+	return s.BatchWindow > 0, time.Time{}
+}
+
+// due reports whether held notifications on a channel, the oldest held at
+// oldest, can be delivered at now
+func (s Schedule) due(channel string, oldest, now time.Time) bool {
+	return !s.Quiet[channel].Active(now) && now.Sub(oldest) >= s.BatchWindow
+}
+
+// heldNotification is a message waiting on one channel for quiet hours or
+// its batch window to end
+type heldNotification struct {
+	Channel string      `json:"channel"`
+	Feed    models.Feed `json:"feed"`
+	Number  string      `json:"number,omitempty"` // Release number, to note the feeds that carry it later
+	Message message     `json:"message"`
+	HeldAt  time.Time   `json:"heldAt"`
+}
+
+// queue stores held notifications. The file is read before every change,
+// so one-off CLI checks and the daemon can share it.
+type queue struct {
+	mu    sync.Mutex
+	path  string
+	items []heldNotification // Used when there is no file
+}
+
+func newQueue(path string) *queue {
+	return &queue{path: path}
+}
+
+// update applies change to the held notifications and saves the result
+func (q *queue) update(change func([]heldNotification) []heldNotification) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.path == "" {
+		q.items = change(q.items)
+		return nil
+	}
+
+	items := []heldNotification{}
+	raw, err := os.ReadFile(q.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", q.path, err)
+		}
+	}
+
+	items = change(items)
+	updated, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Only write when something changed
+	if bytes.Equal(updated, raw) || (raw == nil && len(items) == 0) {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(q.path), 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash can't leave half a file
+	tmp := q.path + ".tmp"
+	if err := os.WriteFile(tmp, updated, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, q.path)
+}
+
+// flushInterval is how often Run delivers the held notifications that are due
+const flushInterval = 30 * time.Second
+
+// hold queues a notification on a channel until its quiet hours or batch
+// window are over
+func (n *Notifier) hold(feed models.Feed, number, channel string, msg message, now, quietUntil time.Time) {
+	err := n.queue.update(func(items []heldNotification) []heldNotification {
+		return append(items, heldNotification{Channel: channel, Feed: feed, Number: number, Message: msg, HeldAt: now})
+	})
+	if err != nil {
+		log.Printf("⚠️ [%s] Failed to hold %s notification: %v\n", feed.Name, channelNames[channel], err)
+		n.publishResult(context.Background(), feed, channel, false, err)
+		return
+	}
+
+	if !quietUntil.IsZero() {
+		log.Printf("🌙 [%s] Quiet hours, holding the %s notification until %s\n", feed.Name, channelNames[channel], quietUntil.Format("15:04"))
+	} else {
+		log.Printf("📦 [%s] Batching the %s notification for %s\n", feed.Name, channelNames[channel], n.schedule.BatchWindow)
+	}
+}
+
+// noteSources lists every feed that carried a release on its notifications
+// that are still held. Notifications already sent can't be amended.
+func (n *Notifier) noteSources(release events.ReleaseDetected, sources []string) {
+	err := n.queue.update(func(items []heldNotification) []heldNotification {
+		for i := range items {
+			item := &items[i]
+			if item.Number == release.Info.Number && item.Feed.SeriesID == release.Feed.SeriesID && item.Feed.OwnerID == release.Feed.OwnerID {
+				item.Message.Sources = sources
+			}
+		}
+		return items
+	})
+	if err != nil {
+		log.Printf("⚠️ [%s] Failed to note the source on held notifications: %v\n", release.Feed.Name, err)
+	}
+}
+
+// Flush delivers the held notifications that are due, one message per
+// channel and user: the release itself if it is alone, a summary otherwise
+func (n *Notifier) Flush(ctx context.Context) {
+	now := time.Now()
+	groups := make(map[string][]heldNotification)
+	order := []string{}
+
+	err := n.queue.update(func(items []heldNotification) []heldNotification {
+		oldest := make(map[string]time.Time)
+		for _, item := range items {
+			key := item.Channel + "\x00" + item.Feed.OwnerID
+			if first, ok := oldest[key]; !ok || item.HeldAt.Before(first) {
+				oldest[key] = item.HeldAt
+			}
+		}
+
+		remaining := make([]heldNotification, 0, len(items))
+		for _, item := range items {
+			key := item.Channel + "\x00" + item.Feed.OwnerID
+			if !n.schedule.due(item.Channel, oldest[key], now) {
+				remaining = append(remaining, item)
+				continue
+			}
+			if _, ok := groups[key]; !ok {
+				order = append(order, key)
+			}
+			groups[key] = append(groups[key], item)
+		}
+		return remaining
+	})
+	if err != nil {
+		log.Printf("⚠️ Failed to read held notifications: %v\n", err)
+		return
+	}
+
+	// Notifications that couldn't be sent go back to the queue for the next
+	// flush, unless their channel is no longer configured
+	failed := []heldNotification{}
+	for _, key := range order {
+		err := n.deliverHeld(ctx, groups[key])
+		if err != nil && !errors.Is(err, errNotConfigured) {
+			failed = append(failed, groups[key]...)
+		}
+	}
+	if len(failed) == 0 {
+		return
+	}
+	err = n.queue.update(func(items []heldNotification) []heldNotification {
+		return append(failed, items...)
+	})
+	if err != nil {
+		log.Printf("⚠️ Failed to hold %d undelivered notification(s) for retry: %v\n", len(failed), err)
+	}
+}
+
+// deliverHeld sends the held notifications of one channel and user
+func (n *Notifier) deliverHeld(ctx context.Context, items []heldNotification) error {
+	channel := items[0].Channel
+	target := n.targetFor(items[0].Feed.OwnerID)
+
+	gotify, embed := items[0].Message.gotify(), items[0].Message.embed()
+	if len(items) > 1 {
+		gotify, embed = summaryGotify(items), summaryEmbed(items)
+	}
+
+	err := n.send(ctx, channel, target, gotify, embed)
+	if errors.Is(err, errNotConfigured) {
+		log.Printf("⚠️ Dropping %d held %s notification(s): %v\n", len(items), channelNames[channel], err)
+	} else if err != nil {
+		log.Printf("⚠️ Failed to deliver %d held %s notification(s), retrying later: %v\n", len(items), channelNames[channel], err)
+		return err
+	} else {
+		log.Printf("📬 Delivered %d held %s notification(s)\n", len(items), channelNames[channel])
+	}
+
+	for _, item := range items {
+		n.publishResult(ctx, item.Feed, channel, false, err)
+	}
+	return err
+}
+
+// Run delivers held notifications as they come due until ctx is cancelled
+func (n *Notifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	n.Flush(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n.Flush(ctx)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// Package opml converts feeds to and from OPML 2.0, the subscription list
+// format most RSS readers import and export.
+package opml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"shinkan-rebirth/internal/models"
+)
+
+// Document is an OPML 2.0 file. Shinkan specific feed settings are carried
+// in the feedType, searchText and anilistUrl outline attributes, which
+// other readers ignore.
+type Document struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    Head     `xml:"head"`
+	Body    Body     `xml:"body"`
+}
+
+type Head struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type Body struct {
+	Outlines []Outline `xml:"outline"`
+}
+
+type Outline struct {
+	Text       string    `xml:"text,attr"`
+	Title      string    `xml:"title,attr,omitempty"`
+	Type       string    `xml:"type,attr,omitempty"`
+	XMLURL     string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL    string    `xml:"htmlUrl,attr,omitempty"`
+	FeedType   string    `xml:"feedType,attr,omitempty"`
+	SearchText string    `xml:"searchText,attr,omitempty"`
+	AnilistURL string    `xml:"anilistUrl,attr,omitempty"`
+	Outlines   []Outline `xml:"outline"`
+}
+
+// Parse reads feeds from an OPML document. Folder outlines become the
+// category of the feeds inside them; for nested folders the innermost one
+// wins. Feeds without a feedType attribute are imported as manga.
+func Parse(r io.Reader) ([]models.Feed, error) {
+	var doc Document
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		// Go only decodes UTF-8, most OPML files are anyway
+		if strings.EqualFold(charset, "utf-8") || strings.EqualFold(charset, "us-ascii") {
+			return input, nil
+		}
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid OPML: %w", err)
+	}
+
+	feeds := make([]models.Feed, 0)
+	collect(doc.Body.Outlines, "", &feeds)
+	return feeds, nil
+}
+
+func collect(outlines []Outline, category string, feeds *[]models.Feed) {
+	for _, outline := range outlines {
+		if outline.XMLURL == "" {
+			// A folder
+			name := outline.Text
+			if name == "" {
+				name = outline.Title
+			}
+			collect(outline.Outlines, name, feeds)
+			continue
+		}
+
+		name := outline.Title
+		if name == "" {
+			name = outline.Text
+		}
+		if name == "" {
+			name = outline.XMLURL
+		}
+
+		feed := models.Feed{
+			Name:     name,
+			RSSUrl:   outline.XMLURL,
+			Type:     models.FeedTypeManga,
+			Category: category,
+		}
+		if outline.FeedType == string(models.FeedTypeAnime) {
+			feed.Type = models.FeedTypeAnime
+		}
+		if outline.SearchText != "" {
+			searchText := outline.SearchText
+			feed.SearchText = &searchText
+		}
+		if outline.AnilistURL != "" {
+			anilistUrl := outline.AnilistURL
+			feed.AnilistUrl = &anilistUrl
+		}
+
+		*feeds = append(*feeds, feed)
+	}
+}
+
+// Write renders feeds as an OPML document with one folder per category.
+// Uncategorized feeds are written at the top level.
+func Write(w io.Writer, title string, feeds []models.Feed) error {
+	doc := Document{
+		Version: "2.0",
+		Head: Head{
+			Title:       title,
+			DateCreated: time.Now().Format(time.RFC1123Z),
+		},
+	}
+
+	folders := make(map[string]*Outline)
+	var order []string
+
+	for _, feed := range feeds {
+		outline := Outline{
+			Text:     feed.Name,
+			Title:    feed.Name,
+			Type:     "rss",
+			XMLURL:   feed.RSSUrl,
+			FeedType: string(feed.Type),
+		}
+		if feed.SearchText != nil {
+			outline.SearchText = *feed.SearchText
+		}
+		if feed.AnilistUrl != nil && *feed.AnilistUrl != "" {
+			outline.AnilistURL = *feed.AnilistUrl
+			outline.HTMLURL = *feed.AnilistUrl
+		}
+
+		if feed.Category == "" {
+			doc.Body.Outlines = append(doc.Body.Outlines, outline)
+			continue
+		}
+
+		folder, ok := folders[feed.Category]
+		if !ok {
+			folder = &Outline{Text: feed.Category, Title: feed.Category}
+			folders[feed.Category] = folder
+			order = append(order, feed.Category)
+		}
+		folder.Outlines = append(folder.Outlines, outline)
+	}
+
+	sort.Strings(order)
+	for _, category := range order {
+		doc.Body.Outlines = append(doc.Body.Outlines, *folders[category])
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,272 @@
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"shinkan-rebirth/internal/events"
+	"shinkan-rebirth/internal/models"
+)
+
+// How many days of rollups are kept
+const retentionDays = 90
+
+const dayFormat = "2006-01-02"
+
+// Stats holds runtime counters built from checker and notifier events
+type Stats struct {
+	TotalChecks       int     `json:"totalChecks"`
+	SuccessfulChecks  int     `json:"successfulChecks"`
+	FailedChecks      int     `json:"failedChecks"`
+	NotificationsSent int     `json:"notificationsSent"`
+	LastCheckTime     *string `json:"lastCheckTime"`
+}
+
+// Day is the rollup of one calendar day, in local time
+type Day struct {
+	Date          string `json:"date"`
+	Checks        int    `json:"checks"`
+	Failures      int    `json:"failures"`
+	Notifications int    `json:"notifications"`
+}
+
+type fileData struct {
+	Totals Stats `json:"totals"`
+	Daily  []Day `json:"daily"`
+}
+
+// Collector keeps Stats up to date by handling bus events. Counters and
+// daily rollups are saved to a JSON file so they survive restarts.
+type Collector struct {
+	mu       sync.RWMutex
+	stats    Stats
+	daily    map[string]*Day
+	filePath string
+	dirty    bool
+}
+
+// New creates a collector, loading previously saved stats from filePath
+func New(filePath string) (*Collector, error) {
+	c := &Collector{
+		daily:    make(map[string]*Day),
+		filePath: filePath,
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	raw, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var data fileData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filePath, err)
+	}
+
+	c.stats = data.Totals
+	for i := range data.Daily {
+		day := data.Daily[i]
+		c.daily[day.Date] = &day
+	}
+	c.prune(time.Now())
+
+	return c, nil
+}
+
+func (c *Collector) HandleEvent(ctx context.Context, event events.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch e := event.(type) {
+	case events.CheckStarted:
+		lastCheckTime := time.Now().Format(time.RFC3339)
+		c.stats.LastCheckTime = &lastCheckTime
+	case events.FeedChecked:
+		c.stats.TotalChecks++
+		c.stats.SuccessfulChecks++
+		c.today().Checks++
+	case events.FeedFailed:
+		c.stats.TotalChecks++
+		c.stats.FailedChecks++
+		today := c.today()
+		today.Checks++
+		today.Failures++
+	case events.NotificationSent:
+		if !e.Test && e.Error == "" {
+			c.stats.NotificationsSent++
+			c.today().Notifications++
+		}
+	default:
+		return
+	}
+
+	c.dirty = true
+}
+
+// today returns the rollup for the current day, must be called with mu held
+func (c *Collector) today() *Day {
+	date := time.Now().Format(dayFormat)
+	day, ok := c.daily[date]
+	if !ok {
+		day = &Day{Date: date}
+		c.daily[date] = day
+	}
+	return day
+}
+
+// prune drops rollups older than the retention period, must be called with
+// mu held
+func (c *Collector) prune(now time.Time) {
+	cutoff := now.AddDate(0, 0, -retentionDays).Format(dayFormat)
+	for date := range c.daily {
+		if date <= cutoff {
+			delete(c.daily, date)
+		}
+	}
+}
+
+func (c *Collector) Get() Stats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.stats
+}
+
+// History returns one rollup per day for the last days days, oldest first.
+// Days without activity are included with zero counts.
+func (c *Collector) History(days int) []Day {
+	if days > retentionDays {
+		days = retentionDays
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := time.Now()
+	history := make([]Day, 0, days)
+	for i := days - 1; i >= 0; i-- {
+		date := now.AddDate(0, 0, -i).Format(dayFormat)
+		if day, ok := c.daily[date]; ok {
+			history = append(history, *day)
+		} else {
+			history = append(history, Day{Date: date})
+		}
+	}
+	return history
+}
+
+func (c *Collector) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats = Stats{}
+	c.daily = make(map[string]*Day)
+	c.dirty = true
+}
+
+// Save writes the stats to disk if anything changed since the last save
+func (c *Collector) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+
+	c.prune(time.Now())
+
+	data := fileData{Totals: c.stats, Daily: make([]Day, 0, len(c.daily))}
+	for _, date := range sortedDates(c.daily) {
+		data.Daily = append(data.Daily, *c.daily[date])
+	}
+
+	raw, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash can't leave half a file
+	tmp := c.filePath + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, c.filePath); err != nil {
+		return err
+	}
+
+	c.dirty = false
+	return nil
+}
+
+// Run saves the stats every interval until ctx is cancelled. Callers should
+// Save once more after the last events have been handled.
+func (c *Collector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Save(); err != nil {
+				log.Printf("⚠️ Failed to save stats: %v\n", err)
+			}
+		}
+	}
+}
+
+func sortedDates(daily map[string]*Day) []string {
+	dates := make([]string, 0, len(daily))
+	for date := range daily {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+	return dates
+}
+
+// Summarize combines the counters with figures computed from a feed list
+func Summarize(counters Stats, feeds []models.Feed) models.Stats {
+	summary := models.Stats{
+		TotalChecks:       counters.TotalChecks,
+		SuccessfulChecks:  counters.SuccessfulChecks,
+		FailedChecks:      counters.FailedChecks,
+		NotificationsSent: counters.NotificationsSent,
+		LastCheckTime:     counters.LastCheckTime,
+		TotalFeeds:        len(feeds),
+		ErrorKinds:        make(map[models.ErrorKind]int),
+	}
+
+	categories := make(map[string]bool)
+	for _, feed := range feeds {
+		if feed.FailCount > 0 {
+			summary.FeedsWithErrors++
+		}
+		if feed.ErrorKind != "" {
+			summary.ErrorKinds[feed.ErrorKind]++
+		}
+		if feed.LastChecked == nil {
+			summary.FeedsNeverChecked++
+		}
+
+		category := feed.Category
+		if category == "" {
+			category = "Uncategorized"
+		}
+		categories[category] = true
+	}
+	summary.Categories = len(categories)
+
+	return summary
+}
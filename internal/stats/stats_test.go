@@ -0,0 +1,76 @@
+package stats
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"shinkan-rebirth/internal/events"
+	"shinkan-rebirth/internal/models"
+)
+
+func TestHandleEventCountsChecksAndNotifications(t *testing.T) {
+	c, err := New(filepath.Join(t.TempDir(), "stats.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bus := events.New()
+	bus.Handle(c.HandleEvent)
+
+	ctx := context.Background()
+	bus.Publish(ctx, events.CheckStarted{})
+	bus.Publish(ctx, events.FeedChecked{Feed: models.Feed{ID: "1"}})
+	bus.Publish(ctx, events.FeedChecked{Feed: models.Feed{ID: "2"}})
+	bus.Publish(ctx, events.FeedFailed{Feed: models.Feed{ID: "3"}, Error: "timeout"})
+	bus.Publish(ctx, events.NotificationSent{Channel: "gotify"})
+	bus.Publish(ctx, events.NotificationSent{Channel: "discord", Error: "unreachable"})
+	bus.Publish(ctx, events.NotificationSent{Channel: "gotify", Test: true})
+	bus.Publish(ctx, events.ReleaseDuplicate{Title: "Chapter 1"}) // Not counted
+
+	got := c.Get()
+	if got.TotalChecks != 3 || got.SuccessfulChecks != 2 || got.FailedChecks != 1 {
+		t.Errorf("checks = %d total, %d ok, %d failed, want 3, 2, 1", got.TotalChecks, got.SuccessfulChecks, got.FailedChecks)
+	}
+	if got.NotificationsSent != 1 {
+		t.Errorf("notifications = %d, want 1, failed and test ones don't count", got.NotificationsSent)
+	}
+	if got.LastCheckTime == nil {
+		t.Error("last check time not set by check-started")
+	}
+
+}
+
+func TestSaveKeepsCountersAndDailyHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.json")
+	c, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	c.HandleEvent(ctx, events.FeedChecked{Feed: models.Feed{ID: "1"}})
+	c.HandleEvent(ctx, events.FeedChecked{Feed: models.Feed{ID: "2"}})
+	c.HandleEvent(ctx, events.FeedFailed{Feed: models.Feed{ID: "3"}, Error: "timeout"})
+	c.HandleEvent(ctx, events.NotificationSent{Channel: "gotify"})
+
+	history := c.History(1)
+	if len(history) != 1 || history[0].Checks != 3 || history[0].Failures != 1 || history[0].Notifications != 1 {
+		t.Errorf("today = %+v, want 3 checks, 1 failure, 1 notification", history)
+	}
+
+	// Saved counters and rollups are loaded again
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.Get().TotalChecks != 3 {
+		t.Errorf("reloaded total checks = %d, want 3", reloaded.Get().TotalChecks)
+	}
+	if history := reloaded.History(1); len(history) != 1 || history[0].Checks != 3 {
+		t.Errorf("reloaded today = %+v, want 3 checks", history)
+	}
+}
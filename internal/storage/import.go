@@ -0,0 +1,371 @@
+package storage
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"shinkan-rebirth/internal/models"
+)
+
+// ImportStrategy decides what happens when an imported feed has the same
+// RSS URL as an existing one
+type ImportStrategy string
+
+const (
+	// StrategySkip leaves the existing feed untouched
+	StrategySkip ImportStrategy = "skip"
+	// StrategyOverwrite replaces the existing feed, state included
+	StrategyOverwrite ImportStrategy = "overwrite"
+	// StrategyMerge only fills in fields the existing feed doesn't have
+	StrategyMerge ImportStrategy = "merge"
+	// StrategyKeepState takes the imported settings but keeps the existing
+	// last chapter, check times and errors
+	StrategyKeepState ImportStrategy = "keep_state"
+)
+
+// Actions in an ImportReport
+const (
+	ImportAdded       = "added"
+	ImportSkipped     = "skipped"
+	ImportOverwritten = "overwritten"
+	ImportMerged      = "merged"
+	ImportKeptState   = "kept_state"
+)
+
+func (s ImportStrategy) Valid() bool {
+	switch s {
+	case StrategySkip, StrategyOverwrite, StrategyMerge, StrategyKeepState:
+		return true
+	}
+	return false
+}
+
+type ImportOptions struct {
+	Strategy   ImportStrategy            // For conflicts without their own strategy, defaults to skip
+	Strategies map[string]ImportStrategy // Per feed, keyed by RSS URL
+	// PreserveState keeps lastChapter, lastItemId, lastEpisode, a held
+	// episode, lastChecked and lastSuccess from the imported data instead of
+	// starting the feed from scratch
+	PreserveState bool
+	DryRun        bool // Report what would change without saving
+	// Series of an export, for the titles, aliases and progress of the
+	// series imported feeds belong to. Series the user has are kept as is,
+	// and only joined by imported feeds if listed here.
+	Series []models.Series
+	// Validate rejects feeds with source, torrent or release settings that
+	// adding the feed would refuse. They are skipped with the error as the
+	// reason.
+	Validate func(models.Feed) error
+}
+
+// ImportChange is what happened to one imported feed
+type ImportChange struct {
+	Action   string         `json:"action"`
+	Reason   string         `json:"reason,omitempty"`
+	Feed     models.Feed    `json:"feed"`               // The feed as stored (or as it would be)
+	Existing *models.Feed   `json:"existing,omitempty"` // The feed it conflicted with
+	Strategy ImportStrategy `json:"strategy,omitempty"`
+	Fields   []string       `json:"fields,omitempty"` // Fields that changed on the existing feed
+}
+
+type ImportReport struct {
+	DryRun      bool           `json:"dryRun"`
+	Added       int            `json:"added"`
+	Skipped     int            `json:"skipped"`
+	Overwritten int            `json:"overwritten"`
+	Merged      int            `json:"merged"`
+	KeptState   int            `json:"keptState"`
+	Changes     []ImportChange `json:"changes"`
+}
+
+// Import adds feeds to a user's list, resolving conflicts with existing
+// feeds (same RSS URL) according to opts
+func (s *Storage) Import(ownerID string, feeds []models.Feed, opts ImportOptions) (ImportReport, error) {
+	if opts.Strategy == "" {
+		opts.Strategy = StrategySkip
+	}
+	if !opts.Strategy.Valid() {
+		return ImportReport{}, fmt.Errorf("unknown import strategy %q", opts.Strategy)
+	}
+	for url, strategy := range opts.Strategies {
+		if !strategy.Valid() {
+			return ImportReport{}, fmt.Errorf("unknown import strategy %q for %s", strategy, url)
+		}
+	}
+
+	data, err := s.read()
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	// Index of the user's feeds by RSS URL
+	existing := make(map[string]int)
+	for i, feed := range data.Feeds {
+		if feed.OwnerID == ownerID {
+			existing[feed.RSSUrl] = i
+		}
+	}
+
+	// Series the user has and the ones the import defines, so imported
+	// series IDs don't join an unrelated series of the user
+	owned := make(map[string]bool)
+	for _, series := range data.Series {
+		if series.OwnerID == ownerID {
+			owned[series.ID] = true
+		}
+	}
+	defined := make(map[string]bool)
+	for _, series := range opts.Series {
+		defined[series.ID] = true
+	}
+	renamed := make(map[string]string)
+
+	report := ImportReport{DryRun: opts.DryRun, Changes: make([]ImportChange, 0, len(feeds))}
+	seen := make(map[string]bool)
+	now := time.Now()
+
+	for n, feed := range feeds {
+		if feed.RSSUrl == "" {
+			report.Skipped++
+			report.Changes = append(report.Changes, ImportChange{Action: ImportSkipped, Reason: "missing RSS URL", Feed: feed})
+			continue
+		}
+		if seen[feed.RSSUrl] {
+			report.Skipped++
+			report.Changes = append(report.Changes, ImportChange{Action: ImportSkipped, Reason: "duplicate in import", Feed: feed})
+			continue
+		}
+		seen[feed.RSSUrl] = true
+		if opts.Validate != nil {
+			if err := opts.Validate(feed); err != nil {
+				report.Skipped++
+				report.Changes = append(report.Changes, ImportChange{Action: ImportSkipped, Reason: err.Error(), Feed: feed})
+				continue
+			}
+		}
+		if !models.ValidSeriesID(feed.SeriesID) {
+			feed.SeriesID = ""
+		}
+
+		i, conflict := existing[feed.RSSUrl]
+		if owned[feed.SeriesID] && !defined[feed.SeriesID] && !(conflict && data.Feeds[i].SeriesID == feed.SeriesID) {
+			id, ok := renamed[feed.SeriesID]
+			if !ok {
+				id = fmt.Sprintf("%d%d", now.UnixNano(), n)
+				renamed[feed.SeriesID] = id
+			}
+			feed.SeriesID = id
+		}
+
+		if !conflict {
+			added := newImportedFeed(feed, ownerID, opts.PreserveState)
+			added.ID = fmt.Sprintf("%d%d", now.UnixNano(), n)
+			added.AddedAt = now.Format(time.RFC3339)
+
+			data.Feeds = append(data.Feeds, added)
+			report.Added++
+			report.Changes = append(report.Changes, ImportChange{Action: ImportAdded, Feed: added})
+			continue
+		}
+
+		strategy := opts.Strategy
+		if perFeed, ok := opts.Strategies[feed.RSSUrl]; ok {
+			strategy = perFeed
+		}
+
+		before := data.Feeds[i]
+		change := ImportChange{Existing: &before, Strategy: strategy}
+
+		switch strategy {
+		case StrategySkip:
+			change.Action = ImportSkipped
+			change.Reason = "already exists"
+			change.Feed = before
+			report.Skipped++
+		case StrategyOverwrite:
+			data.Feeds[i] = overwriteFeed(before, feed, opts.PreserveState)
+			change.Action = ImportOverwritten
+			report.Overwritten++
+		case StrategyMerge:
+			data.Feeds[i] = mergeFeed(before, feed)
+			change.Action = ImportMerged
+			report.Merged++
+		case StrategyKeepState:
+			data.Feeds[i] = keepStateFeed(before, feed)
+			change.Action = ImportKeptState
+			report.KeptState++
+		}
+
+		if change.Action != ImportSkipped {
+			change.Feed = data.Feeds[i]
+			change.Fields = changedFields(before, data.Feeds[i])
+		}
+		report.Changes = append(report.Changes, change)
+	}
+
+	if opts.DryRun || report.Added+report.Overwritten+report.Merged+report.KeptState == 0 {
+		return report, nil
+	}
+
+	ensureSeries(&data, opts.Series)
+	for _, change := range report.Changes {
+		if change.Existing != nil && change.Existing.SeriesID != change.Feed.SeriesID {
+			pruneSeries(&data, ownerID, change.Existing.SeriesID)
+		}
+	}
+
+	if err := s.write(data); err != nil {
+		return ImportReport{}, err
+	}
+
+	return report, nil
+}
+
+// ImportFeeds adds feeds whose RSS URL the user doesn't have yet, starting
+// them from scratch. It returns how many were imported and skipped.
+func (s *Storage) ImportFeeds(ownerID string, feeds []models.Feed) (int, int, error) {
+	report, err := s.Import(ownerID, feeds, ImportOptions{Strategy: StrategySkip})
+	if err != nil {
+		return 0, 0, err
+	}
+	return report.Added, report.Skipped, nil
+}
+
+// newImportedFeed prepares an imported feed for storage
+func newImportedFeed(feed models.Feed, ownerID string, preserveState bool) models.Feed {
+	feed.OwnerID = ownerID
+	feed.FailCount = 0
+	feed.LastError = nil
+	feed.ErrorKind = ""
+	feed.ErrorStatus = 0
+
+	if !preserveState {
+		feed.LastChecked = nil
+		feed.LastSuccess = nil
+		feed.LastChapter = nil
+		feed.LastItemID = nil
+		feed.LastEpisode = nil
+		feed.Pending = nil
+	}
+
+	if feed.Type == "" {
+		feed.Type = models.FeedTypeManga
+	}
+	if feed.Category == "" {
+		feed.Category = "Uncategorized"
+	}
+
+	return feed
+}
+
+// overwriteFeed replaces everything but the identity of the existing feed
+func overwriteFeed(existing, imported models.Feed, preserveState bool) models.Feed {
+	feed := newImportedFeed(imported, existing.OwnerID, preserveState)
+	feed.ID = existing.ID
+	feed.AddedAt = existing.AddedAt
+	if feed.SeriesID == "" {
+		feed.SeriesID = existing.SeriesID
+	}
+	return feed
+}
+
+// keepStateFeed takes the imported settings and the existing check state
+func keepStateFeed(existing, imported models.Feed) models.Feed {
+	feed := overwriteFeed(existing, imported, false)
+	feed.LastChecked = existing.LastChecked
+	feed.LastSuccess = existing.LastSuccess
+	feed.LastChapter = existing.LastChapter
+	feed.LastItemID = existing.LastItemID
+	feed.LastEpisode = existing.LastEpisode
+	feed.Pending = existing.Pending
+	feed.LastError = existing.LastError
+	feed.ErrorKind = existing.ErrorKind
+	feed.ErrorStatus = existing.ErrorStatus
+	feed.FailCount = existing.FailCount
+	return feed
+}
+
+// mergeFeed fills in what the existing feed is missing from the imported one
+func mergeFeed(existing, imported models.Feed) models.Feed {
+	feed := existing
+
+	if feed.Name == "" {
+		feed.Name = imported.Name
+	}
+	if (feed.Category == "" || feed.Category == "Uncategorized") && imported.Category != "" {
+		feed.Category = imported.Category
+	}
+	if isEmpty(feed.AnilistUrl) {
+		feed.AnilistUrl = imported.AnilistUrl
+	}
+	if isEmpty(feed.SearchText) {
+		feed.SearchText = imported.SearchText
+	}
+	if isEmpty(feed.Cover) {
+		feed.Cover = imported.Cover
+	}
+	if feed.Source == nil {
+		feed.Source = imported.Source
+	}
+	if feed.Torrent == nil {
+		feed.Torrent = imported.Torrent
+	}
+	if feed.Preferences == nil {
+		feed.Preferences = imported.Preferences
+	}
+	if feed.SeriesID == "" {
+		feed.SeriesID = imported.SeriesID
+	}
+	if feed.LastChapter == nil && imported.LastChapter != nil {
+		feed.LastChapter = imported.LastChapter
+		feed.LastItemID = imported.LastItemID
+	}
+	if feed.LastEpisode == nil && imported.LastEpisode != nil {
+		feed.LastEpisode = imported.LastEpisode
+	}
+	if feed.LastChecked == nil && imported.LastChecked != nil {
+		feed.LastChecked = imported.LastChecked
+	}
+
+	return feed
+}
+
+// changedFields lists the JSON names of the fields that differ
+func changedFields(before, after models.Feed) []string {
+	fields := make([]string, 0)
+	check := func(name string, changed bool) {
+		if changed {
+			fields = append(fields, name)
+		}
+	}
+
+	check("name", before.Name != after.Name)
+	check("type", before.Type != after.Type)
+	check("category", before.Category != after.Category)
+	check("anilistUrl", deref(before.AnilistUrl) != deref(after.AnilistUrl))
+	check("searchText", deref(before.SearchText) != deref(after.SearchText))
+	check("cover", deref(before.Cover) != deref(after.Cover))
+	check("source", !reflect.DeepEqual(before.Source, after.Source))
+	check("torrent", !reflect.DeepEqual(before.Torrent, after.Torrent))
+	check("preferences", !reflect.DeepEqual(before.Preferences, after.Preferences))
+	check("seriesId", before.SeriesID != after.SeriesID)
+	check("lastChapter", deref(before.LastChapter) != deref(after.LastChapter))
+	check("lastItemId", deref(before.LastItemID) != deref(after.LastItemID))
+	check("lastEpisode", deref(before.LastEpisode) != deref(after.LastEpisode))
+	check("lastChecked", deref(before.LastChecked) != deref(after.LastChecked))
+	check("lastError", deref(before.LastError) != deref(after.LastError))
+
+	return fields
+}
+
+func isEmpty(s *string) bool {
+	return s == nil || *s == ""
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
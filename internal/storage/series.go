@@ -0,0 +1,262 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"shinkan-rebirth/internal/models"
+)
+
+var ErrSeriesNotFound = errors.New("series not found")
+
+func seriesKey(ownerID, id string) string {
+	return ownerID + "\x00" + id
+}
+
+// GetAllSeries returns the series of every user
+func (s *Storage) GetAllSeries() ([]models.Series, error) {
+	data, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	return data.Series, nil
+}
+
+// GetUserSeries returns the series owned by a user
+func (s *Storage) GetUserSeries(ownerID string) ([]models.Series, error) {
+	data, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	owned := make([]models.Series, 0)
+	for _, series := range data.Series {
+		if series.OwnerID == ownerID {
+			owned = append(owned, series)
+		}
+	}
+	return owned, nil
+}
+
+// GetSeries returns a single series if it belongs to the user
+func (s *Storage) GetSeries(ownerID, id string) (*models.Series, error) {
+	data, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range data.Series {
+		if data.Series[i].ID == id && data.Series[i].OwnerID == ownerID {
+			return &data.Series[i], nil
+		}
+	}
+	return nil, ErrSeriesNotFound
+}
+
+// AddSeries creates a series without feeds, which are added to it by their
+// seriesId
+func (s *Storage) AddSeries(series models.Series) (models.Series, error) {
+	data, err := s.read()
+	if err != nil {
+		return models.Series{}, err
+	}
+
+	if series.OwnerID == "" {
+		series.OwnerID = models.DefaultUserID
+	}
+	series.ID = fmt.Sprintf("%d", time.Now().UnixNano())
+	series.CreatedAt = time.Now().Format(time.RFC3339)
+	fillSeriesDefaults(&series)
+
+	data.Series = append(data.Series, series)
+
+	if err := s.write(data); err != nil {
+		return models.Series{}, err
+	}
+	return series, nil
+}
+
+// UpdateSeries changes a user's series. A new category is also applied to
+// the series' feeds, so category filters keep working on feeds.
+func (s *Storage) UpdateSeries(ownerID, id string, updates map[string]interface{}) (*models.Series, error) {
+	data, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	var updated *models.Series
+	for i := range data.Series {
+		series := &data.Series[i]
+		if series.ID != id || series.OwnerID != ownerID {
+			continue
+		}
+
+		if title, ok := updates["title"].(string); ok {
+			series.Title = title
+		}
+		if aliases, ok := updates["aliases"].([]string); ok {
+			series.Aliases = aliases
+		}
+		if anilistID, ok := updates["anilistId"].(int); ok {
+			series.AnilistID = anilistID
+		}
+		if cover, ok := updates["cover"].(string); ok {
+			series.Cover = cover
+		}
+		if status, ok := updates["status"].(models.SeriesStatus); ok {
+			series.Status = status
+		}
+		if category, ok := updates["category"].(string); ok {
+			series.Category = category
+		}
+		if progress, ok := updates["progress"].(string); ok {
+			series.Progress = progress
+		}
+		fillSeriesDefaults(series)
+
+		updated = series
+		break
+	}
+
+	if updated == nil {
+		return nil, ErrSeriesNotFound
+	}
+
+	if _, ok := updates["category"]; ok {
+		for i := range data.Feeds {
+			if data.Feeds[i].OwnerID == ownerID && data.Feeds[i].SeriesID == id {
+				data.Feeds[i].Category = updated.Category
+			}
+		}
+	}
+
+	if err := s.write(data); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteSeries removes a user's series together with its feeds and returns
+// how many feeds were deleted
+func (s *Storage) DeleteSeries(ownerID, id string) (int, error) {
+	data, err := s.read()
+	if err != nil {
+		return 0, err
+	}
+
+	remaining := make([]models.Series, 0, len(data.Series))
+	for _, series := range data.Series {
+		if series.ID != id || series.OwnerID != ownerID {
+			remaining = append(remaining, series)
+		}
+	}
+	if len(remaining) == len(data.Series) {
+		return 0, ErrSeriesNotFound
+	}
+	data.Series = remaining
+
+	feeds := make([]models.Feed, 0, len(data.Feeds))
+	for _, feed := range data.Feeds {
+		if feed.SeriesID != id || feed.OwnerID != ownerID {
+			feeds = append(feeds, feed)
+		}
+	}
+	deleted := len(data.Feeds) - len(feeds)
+	data.Feeds = feeds
+
+	return deleted, s.write(data)
+}
+
+// ensureSeries gives every feed a series. Feeds without a series ID get
+// their own, and series IDs nobody created yet, like a slug shared by two
+// new feeds, are created from the matching template or else the first feed
+// using them.
+func ensureSeries(data *models.Storage, templates []models.Series) {
+	exists := make(map[string]bool)
+	for _, series := range data.Series {
+		exists[seriesKey(series.OwnerID, series.ID)] = true
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	for i := range data.Feeds {
+		feed := &data.Feeds[i]
+		if feed.SeriesID == "" {
+			feed.SeriesID = feed.ID
+		}
+
+		key := seriesKey(feed.OwnerID, feed.SeriesID)
+		if exists[key] {
+			continue
+		}
+		exists[key] = true
+
+		series := seriesFromFeed(*feed, now)
+		for _, template := range templates {
+			if template.ID == feed.SeriesID {
+				series = template
+				series.OwnerID = feed.OwnerID
+				if series.CreatedAt == "" {
+					series.CreatedAt = now
+				}
+				if series.Type == "" {
+					series.Type = feed.Type
+				}
+				break
+			}
+		}
+		fillSeriesDefaults(&series)
+		data.Series = append(data.Series, series)
+	}
+}
+
+// seriesFromFeed is the series a feed stands for on its own
+func seriesFromFeed(feed models.Feed, now string) models.Series {
+	series := models.Series{
+		ID:        feed.SeriesID,
+		OwnerID:   feed.OwnerID,
+		Title:     feed.Name,
+		Type:      feed.Type,
+		Category:  feed.Category,
+		CreatedAt: now,
+	}
+	if feed.AnilistUrl != nil {
+		series.AnilistID = models.ParseAnilistID(*feed.AnilistUrl)
+	}
+	if feed.Cover != nil {
+		series.Cover = *feed.Cover
+	}
+	return series
+}
+
+func fillSeriesDefaults(series *models.Series) {
+	series.Title = strings.TrimSpace(series.Title)
+	if series.Type == "" {
+		series.Type = models.FeedTypeManga
+	}
+	if series.Status == "" {
+		series.Status = models.SeriesCurrent
+	}
+	if series.Category == "" {
+		series.Category = "Uncategorized"
+	}
+}
+
+// pruneSeries removes a user's series once its last feed is gone. Series
+// created empty stay until they have had feeds.
+func pruneSeries(data *models.Storage, ownerID, id string) {
+	for _, feed := range data.Feeds {
+		if feed.OwnerID == ownerID && feed.SeriesID == id {
+			return
+		}
+	}
+
+	remaining := make([]models.Series, 0, len(data.Series))
+	for _, series := range data.Series {
+		if series.ID != id || series.OwnerID != ownerID {
+			remaining = append(remaining, series)
+		}
+	}
+	data.Series = remaining
+}
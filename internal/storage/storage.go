@@ -2,6 +2,7 @@ package storage
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,10 +12,13 @@ import (
 	"shinkan-rebirth/internal/models"
 )
 
+var ErrFeedNotFound = errors.New("feed not found")
+
 type Storage struct {
 	mangaFilePath string
 	animeFilePath string
 	mu            sync.RWMutex
+	writeObserver func(time.Duration)
 }
 
 func New(mangaFilePath, animeFilePath string) *Storage {
@@ -34,7 +38,7 @@ func (s *Storage) ensureDataFiles() {
 	}
 
 	if _, err := os.Stat(s.mangaFilePath); os.IsNotExist(err) {
-		data := models.Storage{Feeds: []models.Feed{}}
+		data := models.Storage{Feeds: []models.Feed{}, Series: []models.Series{}}
 		if err := s.writeToFile(s.mangaFilePath, data); err != nil {
 			panic(fmt.Sprintf("Failed to create manga data file: %v", err))
 		}
@@ -47,7 +51,7 @@ func (s *Storage) ensureDataFiles() {
 	}
 
 	if _, err := os.Stat(s.animeFilePath); os.IsNotExist(err) {
-		data := models.Storage{Feeds: []models.Feed{}}
+		data := models.Storage{Feeds: []models.Feed{}, Series: []models.Series{}}
 		if err := s.writeToFile(s.animeFilePath, data); err != nil {
 			panic(fmt.Sprintf("Failed to create anime data file: %v", err))
 		}
@@ -81,6 +85,8 @@ func (s *Storage) writeToFile(filePath string, data models.Storage) error {
 	}
 	defer file.Close()
 
+	data.Version = models.SchemaVersion
+
 	encoder := json.NewEncoder(file)
 	encoder.SetIndent("", "  ")
 	encoder.SetEscapeHTML(false) // Don't escape HTML characters like &, <, >
@@ -101,13 +107,42 @@ func (s *Storage) read() (models.Storage, error) {
 
 	// Combine both
 	combined := models.Storage{
-		Feeds: append(mangaData.Feeds, animeData.Feeds...),
+		Feeds:  append(mangaData.Feeds, animeData.Feeds...),
+		Series: make([]models.Series, 0, len(mangaData.Series)+len(animeData.Series)),
+	}
+
+	// Feeds from before user accounts belong to the default user
+	for i := range combined.Feeds {
+		if combined.Feeds[i].OwnerID == "" {
+			combined.Feeds[i].OwnerID = models.DefaultUserID
+		}
+	}
+
+	// A series shared by manga and anime feeds is migrated into both files,
+	// the first copy wins
+	seen := make(map[string]bool)
+	for _, series := range append(mangaData.Series, animeData.Series...) {
+		if series.OwnerID == "" {
+			series.OwnerID = models.DefaultUserID
+		}
+		if key := seriesKey(series.OwnerID, series.ID); !seen[key] {
+			seen[key] = true
+			combined.Series = append(combined.Series, series)
+		}
 	}
 
 	return combined, nil
 }
 
+// ObserveWrites registers a function called with the duration of every
+// successful write. It must be set before the storage is used.
+func (s *Storage) ObserveWrites(observer func(time.Duration)) {
+	s.writeObserver = observer
+}
+
 func (s *Storage) write(data models.Storage) error {
+	start := time.Now()
+
 	// Split feeds by type
 	mangaFeeds := make([]models.Feed, 0)
 	animeFeeds := make([]models.Feed, 0)
@@ -120,15 +155,30 @@ func (s *Storage) write(data models.Storage) error {
 		}
 	}
 
+	mangaSeries := make([]models.Series, 0)
+	animeSeries := make([]models.Series, 0)
+
+	for _, series := range data.Series {
+		if series.Type == models.FeedTypeAnime {
+			animeSeries = append(animeSeries, series)
+		} else {
+			mangaSeries = append(mangaSeries, series)
+		}
+	}
+
 	// Write to separate files
-	if err := s.writeToFile(s.mangaFilePath, models.Storage{Feeds: mangaFeeds}); err != nil {
+	if err := s.writeToFile(s.mangaFilePath, models.Storage{Feeds: mangaFeeds, Series: mangaSeries}); err != nil {
 		return err
 	}
 
-	if err := s.writeToFile(s.animeFilePath, models.Storage{Feeds: animeFeeds}); err != nil {
+	if err := s.writeToFile(s.animeFilePath, models.Storage{Feeds: animeFeeds, Series: animeSeries}); err != nil {
 		return err
 	}
 
+	if s.writeObserver != nil {
+		s.writeObserver(time.Since(start))
+	}
+
 	return nil
 }
 
@@ -140,12 +190,50 @@ func (s *Storage) GetFeeds() ([]models.Feed, error) {
 	return data.Feeds, nil
 }
 
+// GetUserFeeds returns the feeds owned by a user
+func (s *Storage) GetUserFeeds(ownerID string) ([]models.Feed, error) {
+	data, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	return filterOwner(data.Feeds, ownerID), nil
+}
+
+// GetUserFeed returns a single feed if it belongs to the user
+func (s *Storage) GetUserFeed(ownerID, id string) (*models.Feed, error) {
+	data, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range data.Feeds {
+		if data.Feeds[i].ID == id && data.Feeds[i].OwnerID == ownerID {
+			return &data.Feeds[i], nil
+		}
+	}
+	return nil, ErrFeedNotFound
+}
+
+func filterOwner(feeds []models.Feed, ownerID string) []models.Feed {
+	owned := make([]models.Feed, 0)
+	for _, feed := range feeds {
+		if feed.OwnerID == ownerID {
+			owned = append(owned, feed)
+		}
+	}
+	return owned
+}
+
 func (s *Storage) AddFeed(feed models.Feed) (models.Feed, error) {
 	data, err := s.read()
 	if err != nil {
 		return models.Feed{}, err
 	}
 
+	if feed.OwnerID == "" {
+		feed.OwnerID = models.DefaultUserID
+	}
+
 	feed.ID = fmt.Sprintf("%d", time.Now().UnixNano())
 	feed.AddedAt = time.Now().Format(time.RFC3339)
 	feed.FailCount = 0
@@ -155,6 +243,8 @@ func (s *Storage) AddFeed(feed models.Feed) (models.Feed, error) {
 	}
 
 	data.Feeds = append(data.Feeds, feed)
+	ensureSeries(&data, nil)
+	feed = data.Feeds[len(data.Feeds)-1]
 
 	if err := s.write(data); err != nil {
 		return models.Feed{}, err
@@ -163,23 +253,64 @@ func (s *Storage) AddFeed(feed models.Feed) (models.Feed, error) {
 	return feed, nil
 }
 
-func (s *Storage) DeleteFeed(id string) error {
+// DeleteFeed removes a feed owned by the user
+func (s *Storage) DeleteFeed(ownerID, id string) error {
 	data, err := s.read()
 	if err != nil {
 		return err
 	}
 
 	newFeeds := make([]models.Feed, 0)
+	seriesID := ""
 	for _, feed := range data.Feeds {
-		if feed.ID != id {
+		if feed.ID != id || feed.OwnerID != ownerID {
 			newFeeds = append(newFeeds, feed)
+		} else {
+			seriesID = feed.SeriesID
 		}
 	}
 
+	if len(newFeeds) == len(data.Feeds) {
+		return ErrFeedNotFound
+	}
+
 	data.Feeds = newFeeds
+	pruneSeries(&data, ownerID, seriesID)
 	return s.write(data)
 }
 
+// DeleteUserFeeds removes every feed and series owned by a user and returns
+// how many feeds were deleted
+func (s *Storage) DeleteUserFeeds(ownerID string) (int, error) {
+	data, err := s.read()
+	if err != nil {
+		return 0, err
+	}
+
+	remaining := make([]models.Feed, 0, len(data.Feeds))
+	for _, feed := range data.Feeds {
+		if feed.OwnerID != ownerID {
+			remaining = append(remaining, feed)
+		}
+	}
+
+	remainingSeries := make([]models.Series, 0, len(data.Series))
+	for _, series := range data.Series {
+		if series.OwnerID != ownerID {
+			remainingSeries = append(remainingSeries, series)
+		}
+	}
+
+	deleted := len(data.Feeds) - len(remaining)
+	if deleted == 0 && len(remainingSeries) == len(data.Series) {
+		return 0, nil
+	}
+
+	data.Feeds = remaining
+	data.Series = remainingSeries
+	return deleted, s.write(data)
+}
+
 func (s *Storage) UpdateFeed(id string, updates map[string]interface{}) (*models.Feed, error) {
 	data, err := s.read()
 	if err != nil {
@@ -187,6 +318,7 @@ func (s *Storage) UpdateFeed(id string, updates map[string]interface{}) (*models
 	}
 
 	var updatedFeed *models.Feed
+	previousSeries := ""
 	for i, feed := range data.Feeds {
 		if feed.ID == id {
 			// Apply updates
@@ -211,14 +343,50 @@ func (s *Storage) UpdateFeed(id string, updates map[string]interface{}) (*models
 			if lastChecked, ok := updates["lastChecked"].(string); ok {
 				data.Feeds[i].LastChecked = &lastChecked
 			}
+			if lastSuccess, ok := updates["lastSuccess"].(string); ok {
+				data.Feeds[i].LastSuccess = &lastSuccess
+			}
 			if lastChapter, ok := updates["lastChapter"].(string); ok {
 				data.Feeds[i].LastChapter = &lastChapter
 			}
+			if lastItemID, ok := updates["lastItemId"].(string); ok {
+				data.Feeds[i].LastItemID = &lastItemID
+			}
 			if lastError, ok := updates["lastError"].(string); ok {
 				data.Feeds[i].LastError = &lastError
 			}
 			if lastError := updates["lastError"]; lastError == nil {
 				data.Feeds[i].LastError = nil
+				data.Feeds[i].ErrorKind = ""
+				data.Feeds[i].ErrorStatus = 0
+			}
+			if errorKind, ok := updates["errorKind"].(models.ErrorKind); ok {
+				data.Feeds[i].ErrorKind = errorKind
+			}
+			if errorStatus, ok := updates["errorStatus"].(int); ok {
+				data.Feeds[i].ErrorStatus = errorStatus
+			}
+			if source, ok := updates["source"].(*models.Source); ok {
+				data.Feeds[i].Source = source
+				// The ID path may have changed, titles are compared until
+				// the next check records a new ID
+				data.Feeds[i].LastItemID = nil
+			}
+			if handoff, ok := updates["torrent"].(*models.TorrentHandoff); ok {
+				data.Feeds[i].Torrent = handoff
+			}
+			if prefs, ok := updates["preferences"].(*models.ReleasePreferences); ok {
+				data.Feeds[i].Preferences = prefs
+			}
+			if lastEpisode, ok := updates["lastEpisode"].(string); ok {
+				data.Feeds[i].LastEpisode = &lastEpisode
+			}
+			if pending, ok := updates["pendingEpisode"].(*models.PendingEpisode); ok {
+				data.Feeds[i].Pending = pending
+			}
+			if seriesID, ok := updates["seriesId"].(string); ok {
+				previousSeries = feed.SeriesID
+				data.Feeds[i].SeriesID = seriesID
 			}
 			if failCount, ok := updates["failCount"].(int); ok {
 				data.Feeds[i].FailCount = failCount
@@ -230,7 +398,16 @@ func (s *Storage) UpdateFeed(id string, updates map[string]interface{}) (*models
 	}
 
 	if updatedFeed == nil {
-		return nil, fmt.Errorf("feed not found")
+		return nil, ErrFeedNotFound
+	}
+
+	// Moving a feed to another series creates that series if needed and
+	// removes the old one if it was the last feed
+	if _, ok := updates["seriesId"]; ok {
+		ensureSeries(&data, nil)
+		if previousSeries != updatedFeed.SeriesID {
+			pruneSeries(&data, updatedFeed.OwnerID, previousSeries)
+		}
 	}
 
 	if err := s.write(data); err != nil {
@@ -240,14 +417,15 @@ func (s *Storage) UpdateFeed(id string, updates map[string]interface{}) (*models
 	return updatedFeed, nil
 }
 
-func (s *Storage) GetCategories() ([]string, error) {
+// GetCategories lists the categories used by a user's feeds
+func (s *Storage) GetCategories(ownerID string) ([]string, error) {
 	data, err := s.read()
 	if err != nil {
 		return nil, err
 	}
 
 	categoryMap := make(map[string]bool)
-	for _, feed := range data.Feeds {
+	for _, feed := range filterOwner(data.Feeds, ownerID) {
 		category := feed.Category
 		if category == "" {
 			category = "Uncategorized"
@@ -263,7 +441,8 @@ func (s *Storage) GetCategories() ([]string, error) {
 	return categories, nil
 }
 
-func (s *Storage) SearchFeeds(query string) ([]models.Feed, error) {
+// SearchFeeds searches a user's feeds
+func (s *Storage) SearchFeeds(ownerID, query string) ([]models.Feed, error) {
 	data, err := s.read()
 	if err != nil {
 		return nil, err
@@ -271,7 +450,7 @@ func (s *Storage) SearchFeeds(query string) ([]models.Feed, error) {
 
 	// Simple case-insensitive search
 	results := make([]models.Feed, 0)
-	for _, feed := range data.Feeds {
+	for _, feed := range filterOwner(data.Feeds, ownerID) {
 		if contains(feed.Name, query) ||
 			contains(feed.RSSUrl, query) ||
 			(feed.LastChapter != nil && contains(*feed.LastChapter, query)) {
@@ -311,46 +490,3 @@ func toLower(s string) string {
 	}
 	return string(result)
 }
-
-func (s *Storage) ImportFeeds(feeds []models.Feed) (int, int, error) {
-	data, err := s.read()
-	if err != nil {
-		return 0, 0, err
-	}
-
-	imported := 0
-	skipped := 0
-
-	// Create a map of existing RSS URLs for faster lookup
-	existingUrls := make(map[string]bool)
-	for _, feed := range data.Feeds {
-		existingUrls[feed.RSSUrl] = true
-	}
-
-	for _, feed := range feeds {
-		if existingUrls[feed.RSSUrl] {
-			skipped++
-			continue
-		}
-
-		feed.ID = fmt.Sprintf("%d%d", time.Now().UnixNano(), imported)
-		feed.AddedAt = time.Now().Format(time.RFC3339)
-		feed.FailCount = 0
-		feed.LastChecked = nil
-		feed.LastChapter = nil
-		feed.LastError = nil
-
-		if feed.Category == "" {
-			feed.Category = "Uncategorized"
-		}
-
-		data.Feeds = append(data.Feeds, feed)
-		imported++
-	}
-
-	if err := s.write(data); err != nil {
-		return 0, 0, err
-	}
-
-	return imported, skipped, nil
-}
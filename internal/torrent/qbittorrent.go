@@ -0,0 +1,113 @@
+package torrent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"shinkan-rebirth/internal/models"
+)
+
+// QBittorrent talks to the qBittorrent WebUI API
+type QBittorrent struct {
+	baseURL    string
+	username   string
+	password   string
+	httpClient *http.Client
+	mu         sync.Mutex
+	loggedIn   bool
+}
+
+// NewQBittorrent creates a client for the WebUI at baseURL. Without a
+// username no login is attempted, for WebUIs that bypass authentication for
+// local clients.
+func NewQBittorrent(baseURL, username, password string, timeout time.Duration) *QBittorrent {
+	jar, _ := cookiejar.New(nil)
+	return &QBittorrent{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		username:   username,
+		password:   password,
+		httpClient: &http.Client{Timeout: timeout, Jar: jar},
+	}
+}
+
+func (q *QBittorrent) Add(ctx context.Context, uri string, handoff models.TorrentHandoff) error {
+	form := url.Values{}
+	form.Set("urls", uri)
+	if handoff.SavePath != "" {
+		form.Set("savepath", handoff.SavePath)
+	}
+	if handoff.Category != "" {
+		form.Set("category", handoff.Category)
+	}
+
+	if err := q.login(ctx, false); err != nil {
+		return err
+	}
+	status, body, err := q.post(ctx, "/api/v2/torrents/add", form)
+	if status == http.StatusForbidden {
+		// The session expired, log in again once
+		if err := q.login(ctx, true); err != nil {
+			return err
+		}
+		status, body, err = q.post(ctx, "/api/v2/torrents/add", form)
+	}
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK || strings.TrimSpace(body) == "Fails." {
+		return fmt.Errorf("qBittorrent rejected the torrent (HTTP %d): %s", status, strings.TrimSpace(body))
+	}
+	return nil
+}
+
+// login starts a session unless one is open or no username is set
+func (q *QBittorrent) login(ctx context.Context, force bool) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.username == "" || (q.loggedIn && !force) {
+		return nil
+	}
+
+	form := url.Values{}
+	form.Set("username", q.username)
+	form.Set("password", q.password)
+	status, body, err := q.post(ctx, "/api/v2/auth/login", form)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK || strings.TrimSpace(body) != "Ok." {
+		return fmt.Errorf("qBittorrent login failed (HTTP %d): %s", status, strings.TrimSpace(body))
+	}
+	q.loggedIn = true
+	return nil
+}
+
+func (q *QBittorrent) post(ctx context.Context, path string, form url.Values) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", q.baseURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// The WebUI rejects requests whose Referer doesn't match its host
+	req.Header.Set("Referer", q.baseURL)
+
+	resp, err := q.httpClient.Do(req)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to reach qBittorrent: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return 0, "", err
+	}
+	return resp.StatusCode, string(body), nil
+}
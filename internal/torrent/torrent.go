@@ -0,0 +1,101 @@
+// Package torrent hands new releases of torrent feeds to a qBittorrent or
+// Transmission WebUI, so episodes start downloading as soon as they are
+// detected.
+package torrent
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"shinkan-rebirth/internal/events"
+	"shinkan-rebirth/internal/models"
+)
+
+// Client adds torrents to a torrent client
+type Client interface {
+	// Add starts downloading a magnet link or .torrent URL
+	Add(ctx context.Context, uri string, handoff models.TorrentHandoff) error
+}
+
+// Handoff sends detected releases of feeds with torrent settings to the
+// configured clients
+type Handoff struct {
+	clients map[models.TorrentClient]Client
+	events  events.Publisher
+}
+
+// New creates a hand-off for the given clients; clients that aren't
+// configured are left out of the map
+func New(clients map[models.TorrentClient]Client, publisher events.Publisher) *Handoff {
+	return &Handoff{clients: clients, events: publisher}
+}
+
+// Clients lists the configured clients
+func (h *Handoff) Clients() []models.TorrentClient {
+	clients := make([]models.TorrentClient, 0, len(h.clients))
+	for _, client := range []models.TorrentClient{models.TorrentClientQBittorrent, models.TorrentClientTransmission} {
+		if _, ok := h.clients[client]; ok {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
+
+// Validate checks a feed's torrent settings against the configured clients
+func (h *Handoff) Validate(handoff *models.TorrentHandoff) error {
+	if handoff == nil {
+		return nil
+	}
+	switch handoff.Client {
+	case models.TorrentClientQBittorrent, models.TorrentClientTransmission:
+	default:
+		return fmt.Errorf("unknown torrent client %q", handoff.Client)
+	}
+	if _, ok := h.clients[handoff.Client]; !ok {
+		return fmt.Errorf("%s is not configured", handoff.Client)
+	}
+	return nil
+}
+
+// HandleEvent adds the torrent of every detected release of a feed with
+// torrent settings
+func (h *Handoff) HandleEvent(ctx context.Context, event events.Event) {
+	release, ok := event.(events.ReleaseDetected)
+	if !ok || release.Feed.Torrent == nil {
+		return
+	}
+
+	feed := release.Feed
+	torrent := release.Info.Torrent
+	if torrent == nil {
+		log.Printf("⚠️ [%s] Release %q has no torrent to hand off\n", feed.Name, release.Title)
+		return
+	}
+
+	uri := torrent.Magnet
+	if uri == "" {
+		uri = torrent.URL
+	}
+
+	err := h.add(ctx, uri, *feed.Torrent)
+	if err != nil {
+		log.Printf("⚠️ [%s] Failed to add torrent to %s: %v\n", feed.Name, feed.Torrent.Client, err)
+	} else {
+		log.Printf("🧲 [%s] Added %s to %s\n", feed.Name, release.Title, feed.Torrent.Client)
+	}
+
+	added := events.TorrentAdded{Feed: feed, Client: feed.Torrent.Client, Title: release.Title}
+	if err != nil {
+		added.Error = err.Error()
+	}
+	h.events.Publish(ctx, added)
+}
+
+func (h *Handoff) add(ctx context.Context, uri string, handoff models.TorrentHandoff) error {
+	client, ok := h.clients[handoff.Client]
+	if !ok {
+		return fmt.Errorf("%s is not configured", handoff.Client)
+	}
+	return client.Add(ctx, uri, handoff)
+}
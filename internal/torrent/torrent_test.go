@@ -0,0 +1,239 @@
+package torrent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"shinkan-rebirth/internal/events"
+	"shinkan-rebirth/internal/models"
+)
+
+const testMagnet = "magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567&dn=Show+-+05"
+
+// recorder is a fake publisher keeping every event
+type recorder struct {
+	mu     sync.Mutex
+	events []events.Event
+}
+
+func (r *recorder) Publish(ctx context.Context, event events.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// fakeQBittorrent is a qBittorrent WebUI with one user. Sessions can be
+// expired to make the client log in again.
+type fakeQBittorrent struct {
+	*httptest.Server
+	mu      sync.Mutex
+	session string
+	logins  int
+	added   []map[string]string
+}
+
+func newFakeQBittorrent(t *testing.T) *fakeQBittorrent {
+	q := &fakeQBittorrent{}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/v2/auth/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Referer") != q.URL {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.FormValue("username") != "admin" || r.FormValue("password") != "secret" {
+			w.Write([]byte("Fails."))
+			return
+		}
+
+		q.mu.Lock()
+		q.logins++
+		q.session = time.Now().Format(time.RFC3339Nano)
+		http.SetCookie(w, &http.Cookie{Name: "SID", Value: q.session, Path: "/"})
+		q.mu.Unlock()
+		w.Write([]byte("Ok."))
+	})
+
+	mux.HandleFunc("/api/v2/torrents/add", func(w http.ResponseWriter, r *http.Request) {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+
+		cookie, err := r.Cookie("SID")
+		if err != nil || q.session == "" || cookie.Value != q.session {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		q.added = append(q.added, map[string]string{
+			"urls":     r.FormValue("urls"),
+			"savepath": r.FormValue("savepath"),
+			"category": r.FormValue("category"),
+		})
+		w.Write([]byte("Ok."))
+	})
+
+	q.Server = httptest.NewServer(mux)
+	t.Cleanup(q.Close)
+	return q
+}
+
+func (q *fakeQBittorrent) expireSession() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.session = ""
+}
+
+func TestQBittorrentAdd(t *testing.T) {
+	server := newFakeQBittorrent(t)
+	client := NewQBittorrent(server.URL+"/", "admin", "secret", 5*time.Second)
+	ctx := context.Background()
+	handoff := models.TorrentHandoff{Client: models.TorrentClientQBittorrent, SavePath: "/downloads/anime", Category: "anime"}
+
+	if err := client.Add(ctx, testMagnet, handoff); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Add(ctx, testMagnet, handoff); err != nil {
+		t.Fatal(err)
+	}
+	if server.logins != 1 {
+		t.Errorf("logged in %d times, want the session reused", server.logins)
+	}
+
+	// An expired session is renewed once
+	server.expireSession()
+	if err := client.Add(ctx, testMagnet, handoff); err != nil {
+		t.Fatal(err)
+	}
+	if server.logins != 2 {
+		t.Errorf("logged in %d times, want 2 after the session expired", server.logins)
+	}
+
+	if len(server.added) != 3 {
+		t.Fatalf("added %d torrents, want 3", len(server.added))
+	}
+	if got := server.added[0]; got["urls"] != testMagnet || got["savepath"] != "/downloads/anime" || got["category"] != "anime" {
+		t.Errorf("added %v", got)
+	}
+}
+
+func TestQBittorrentWrongPassword(t *testing.T) {
+	server := newFakeQBittorrent(t)
+	client := NewQBittorrent(server.URL, "admin", "wrong", 5*time.Second)
+
+	if err := client.Add(context.Background(), testMagnet, models.TorrentHandoff{}); err == nil {
+		t.Fatal("added with a wrong password")
+	}
+	if len(server.added) != 0 {
+		t.Errorf("added %d torrents without a session", len(server.added))
+	}
+}
+
+// fakeTransmission is a Transmission RPC endpoint, which answers 409 with
+// a new session ID until the client sends it
+type fakeTransmission struct {
+	*httptest.Server
+	mu        sync.Mutex
+	sessionID string
+	conflicts int
+	added     []map[string]interface{}
+}
+
+func newFakeTransmission(t *testing.T) *fakeTransmission {
+	tr := &fakeTransmission{sessionID: "session-1"}
+	tr.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		tr.mu.Lock()
+		defer tr.mu.Unlock()
+		if r.Header.Get(transmissionSessionHeader) != tr.sessionID {
+			tr.conflicts++
+			w.Header().Set(transmissionSessionHeader, tr.sessionID)
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+
+		var req transmissionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "torrent-add" {
+			json.NewEncoder(w).Encode(transmissionResponse{Result: "invalid request"})
+			return
+		}
+		tr.added = append(tr.added, req.Arguments)
+		json.NewEncoder(w).Encode(transmissionResponse{Result: "success"})
+	}))
+	t.Cleanup(tr.Close)
+	return tr
+}
+
+func TestTransmissionAdd(t *testing.T) {
+	server := newFakeTransmission(t)
+	client := NewTransmission(server.URL, "admin", "secret", 5*time.Second)
+	ctx := context.Background()
+	handoff := models.TorrentHandoff{Client: models.TorrentClientTransmission, SavePath: "/downloads", Category: "anime"}
+
+	if err := client.Add(ctx, testMagnet, handoff); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Add(ctx, testMagnet, handoff); err != nil {
+		t.Fatal(err)
+	}
+	if server.conflicts != 1 {
+		t.Errorf("got %d session conflicts, want the session ID reused after the first", server.conflicts)
+	}
+
+	// Transmission restarted with a new session ID
+	server.mu.Lock()
+	server.sessionID = "session-2"
+	server.mu.Unlock()
+	if err := client.Add(ctx, testMagnet, handoff); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(server.added) != 3 {
+		t.Fatalf("added %d torrents, want 3", len(server.added))
+	}
+	args := server.added[0]
+	labels, _ := args["labels"].([]interface{})
+	if args["filename"] != testMagnet || args["download-dir"] != "/downloads" || len(labels) != 1 || labels[0] != "anime" {
+		t.Errorf("arguments = %v", args)
+	}
+}
+
+func TestHandleEventHandsOffTorrentReleases(t *testing.T) {
+	server := newFakeQBittorrent(t)
+	publisher := &recorder{}
+	handoff := New(map[models.TorrentClient]Client{
+		models.TorrentClientQBittorrent: NewQBittorrent(server.URL, "admin", "secret", 5*time.Second),
+	}, publisher)
+	ctx := context.Background()
+
+	feed := models.Feed{Name: "Show", Type: models.FeedTypeAnime, Torrent: &models.TorrentHandoff{Client: models.TorrentClientQBittorrent}}
+	withTorrent := models.ReleaseInfo{Torrent: &models.TorrentInfo{Magnet: testMagnet}}
+
+	handoff.HandleEvent(ctx, events.ReleaseDetected{Feed: feed, Title: "Show - 05", Info: withTorrent})
+	handoff.HandleEvent(ctx, events.ReleaseDetected{Feed: feed, Title: "Show - 06"})                                          // No torrent in the release
+	handoff.HandleEvent(ctx, events.ReleaseDetected{Feed: models.Feed{Name: "Manga"}, Title: "Chapter 1", Info: withTorrent}) // No hand-off set
+
+	transmissionFeed := feed
+	transmissionFeed.Torrent = &models.TorrentHandoff{Client: models.TorrentClientTransmission}
+	handoff.HandleEvent(ctx, events.ReleaseDetected{Feed: transmissionFeed, Title: "Show - 07", Info: withTorrent})
+
+	if len(server.added) != 1 || server.added[0]["urls"] != testMagnet {
+		t.Errorf("qBittorrent got %v, want only the first release", server.added)
+	}
+	if len(publisher.events) != 2 {
+		t.Fatalf("published %d events, want 2", len(publisher.events))
+	}
+	if added := publisher.events[0].(events.TorrentAdded); added.Error != "" || added.Title != "Show - 05" {
+		t.Errorf("first event = %+v, want Show - 05 added", added)
+	}
+	if added := publisher.events[1].(events.TorrentAdded); added.Error == "" || added.Client != models.TorrentClientTransmission {
+		t.Errorf("second event = %+v, want Transmission not configured", added)
+	}
+}
@@ -0,0 +1,113 @@
+package torrent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"shinkan-rebirth/internal/models"
+)
+
+// Header carrying Transmission's CSRF token
+const transmissionSessionHeader = "X-Transmission-Session-Id"
+
+// Transmission talks to the Transmission RPC API
+type Transmission struct {
+	rpcURL     string
+	username   string
+	password   string
+	httpClient *http.Client
+	mu         sync.Mutex
+	sessionID  string
+}
+
+type transmissionRequest struct {
+	Method    string                 `json:"method"`
+	Arguments map[string]interface{} `json:"arguments"`
+}
+
+type transmissionResponse struct {
+	Result string `json:"result"`
+}
+
+// NewTransmission creates a client for the RPC endpoint at rpcURL, usually
+// http://host:9091/transmission/rpc
+func NewTransmission(rpcURL, username, password string, timeout time.Duration) *Transmission {
+	return &Transmission{
+		rpcURL:     rpcURL,
+		username:   username,
+		password:   password,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+func (t *Transmission) Add(ctx context.Context, uri string, handoff models.TorrentHandoff) error {
+	args := map[string]interface{}{"filename": uri}
+	if handoff.SavePath != "" {
+		args["download-dir"] = handoff.SavePath
+	}
+	if handoff.Category != "" {
+		args["labels"] = []string{handoff.Category}
+	}
+
+	body, err := json.Marshal(transmissionRequest{Method: "torrent-add", Arguments: args})
+	if err != nil {
+		return err
+	}
+
+	resp, err := t.post(ctx, body)
+	if err != nil {
+		return err
+	}
+	if resp.Result != "success" {
+		return fmt.Errorf("Transmission rejected the torrent: %s", resp.Result)
+	}
+	return nil
+}
+
+// post sends an RPC request, fetching a new session ID when Transmission
+// answers 409 Conflict
+func (t *Transmission) post(ctx context.Context, body []byte) (*transmissionResponse, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "POST", t.rpcURL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if t.username != "" {
+			req.SetBasicAuth(t.username, t.password)
+		}
+		t.mu.Lock()
+		req.Header.Set(transmissionSessionHeader, t.sessionID)
+		t.mu.Unlock()
+
+		resp, err := t.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reach Transmission: %w", err)
+		}
+
+		if resp.StatusCode == http.StatusConflict {
+			resp.Body.Close()
+			t.mu.Lock()
+			t.sessionID = resp.Header.Get(transmissionSessionHeader)
+			t.mu.Unlock()
+			continue
+		}
+
+		var result transmissionResponse
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("Transmission returned status %d", resp.StatusCode)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid Transmission response: %w", err)
+		}
+		return &result, nil
+	}
+	return nil, fmt.Errorf("Transmission kept rejecting the session ID")
+}
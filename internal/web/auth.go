@@ -0,0 +1,245 @@
+package web
+
+import (
+	"strings"
+	"time"
+
+	"shinkan-rebirth/internal/auth"
+	"shinkan-rebirth/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	sessionCookie = "shinkan_session"
+	csrfCookie    = "shinkan_csrf"
+	csrfHeader    = "X-CSRF-Token"
+)
+
+// API routes reachable without credentials
+var publicRoutes = map[string]bool{
+	"/api/health":      true,
+	"/api/auth/login":  true,
+	"/api/auth/status": true,
+}
+
+// requireLoginPage sends browsers without a session to the login page
+func (s *Server) requireLoginPage(c *fiber.Ctx) error {
+	if !s.auth.Enabled() {
+		return c.Next()
+	}
+
+	if path := c.Path(); path != "/" && path != "/index.html" {
+		return c.Next()
+	}
+
+	if _, ok := s.auth.Session(c.Cookies(sessionCookie)); !ok {
+		return c.Redirect("/login.html")
+	}
+	return c.Next()
+}
+
+// authenticate accepts either a bearer API key or a session cookie. Read
+// scoped keys may only use safe methods, and cookie sessions need a CSRF
+// token for anything that changes state.
+func (s *Server) authenticate(c *fiber.Ctx) error {
+	if !s.auth.Enabled() {
+		c.Locals("scope", models.ScopeAdmin)
+		c.Locals("userID", models.DefaultUserID)
+		c.Locals("isAdmin", true)
+		return c.Next()
+	}
+
+	if publicRoutes[c.Path()] {
+		return c.Next()
+	}
+
+	safeMethod := c.Method() == fiber.MethodGet || c.Method() == fiber.MethodHead
+
+	if header := c.Get(fiber.HeaderAuthorization); strings.HasPrefix(header, "Bearer ") {
+		key, ok := s.auth.Authenticate(strings.TrimPrefix(header, "Bearer "))
+		if !ok {
+			return c.Status(401).JSON(fiber.Map{"error": "Invalid API key"})
+		}
+		if !safeMethod && key.Scope != models.ScopeAdmin {
+			return c.Status(403).JSON(fiber.Map{"error": "API key is read-only"})
+		}
+		user, err := s.auth.GetUser(key.UserID)
+		if err != nil {
+			// The key outlived its owner
+			return c.Status(401).JSON(fiber.Map{"error": "Invalid API key"})
+		}
+		c.Locals("scope", key.Scope)
+		c.Locals("userID", user.ID)
+		c.Locals("isAdmin", user.IsAdmin)
+		return c.Next()
+	}
+
+	session, ok := s.auth.Session(c.Cookies(sessionCookie))
+	if !ok {
+		return c.Status(401).JSON(fiber.Map{"error": "Authentication required"})
+	}
+	if !safeMethod && !session.ValidCSRF(c.Get(csrfHeader)) {
+		return c.Status(403).JSON(fiber.Map{"error": "Invalid CSRF token"})
+	}
+
+	c.Locals("scope", models.ScopeAdmin)
+	c.Locals("userID", session.UserID)
+	c.Locals("isAdmin", session.IsAdmin)
+	return c.Next()
+}
+
+// requireAdmin rejects read-only API keys even on safe methods
+func requireAdmin(c *fiber.Ctx) error {
+	if scope, _ := c.Locals("scope").(models.Scope); scope != models.ScopeAdmin {
+		return c.Status(403).JSON(fiber.Map{"error": "Admin scope required"})
+	}
+	return c.Next()
+}
+
+// requireAdminUser only lets admin accounts through
+func requireAdminUser(c *fiber.Ctx) error {
+	if isAdmin, _ := c.Locals("isAdmin").(bool); !isAdmin {
+		return c.Status(403).JSON(fiber.Map{"error": "Admin account required"})
+	}
+	return c.Next()
+}
+
+// userID returns the account the request was authenticated as
+func userID(c *fiber.Ctx) string {
+	if id, ok := c.Locals("userID").(string); ok && id != "" {
+		return id
+	}
+	return models.DefaultUserID
+}
+
+func (s *Server) login(c *fiber.Ctx) error {
+	if !s.auth.Enabled() {
+		return c.Status(400).JSON(fiber.Map{"error": "Authentication is disabled"})
+	}
+
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	session, err := s.auth.Login(req.Username, req.Password)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": "Invalid username or password"})
+	}
+
+	secure := c.Protocol() == "https"
+	c.Cookie(&fiber.Cookie{
+		Name:     sessionCookie,
+		Value:    session.Token,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		HTTPOnly: true,
+		Secure:   secure,
+		SameSite: fiber.CookieSameSiteStrictMode,
+	})
+	// Readable by the page so it can echo it back in the CSRF header
+	c.Cookie(&fiber.Cookie{
+		Name:     csrfCookie,
+		Value:    session.CSRFToken,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		Secure:   secure,
+		SameSite: fiber.CookieSameSiteStrictMode,
+	})
+
+	return c.JSON(fiber.Map{"success": true, "csrfToken": session.CSRFToken})
+}
+
+func (s *Server) logout(c *fiber.Ctx) error {
+	s.auth.Logout(c.Cookies(sessionCookie))
+
+	for _, name := range []string{sessionCookie, csrfCookie} {
+		c.Cookie(&fiber.Cookie{
+			Name:    name,
+			Value:   "",
+			Path:    "/",
+			Expires: time.Unix(0, 0),
+		})
+	}
+
+	return c.JSON(fiber.Map{"success": true})
+}
+
+func (s *Server) getAuthStatus(c *fiber.Ctx) error {
+	status := fiber.Map{
+		"enabled":       s.auth.Enabled(),
+		"authenticated": !s.auth.Enabled(),
+	}
+
+	if session, ok := s.auth.Session(c.Cookies(sessionCookie)); ok {
+		status["authenticated"] = true
+		status["csrfToken"] = session.CSRFToken
+		if user, err := s.auth.GetUser(session.UserID); err == nil {
+			status["username"] = user.Username
+			status["isAdmin"] = user.IsAdmin
+		}
+	}
+
+	return c.JSON(status)
+}
+
+func (s *Server) getKeys(c *fiber.Ctx) error {
+	keys, err := s.auth.ListKeys(userID(c))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	for i := range keys {
+		keys[i].Hash = ""
+	}
+	return c.JSON(keys)
+}
+
+func (s *Server) createKey(c *fiber.Ctx) error {
+	var req struct {
+		Name  string       `json:"name"`
+		Scope models.Scope `json:"scope"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	if req.Name == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Name required"})
+	}
+
+	if req.Scope == "" {
+		req.Scope = models.ScopeRead
+	}
+
+	key, plain, err := s.auth.CreateKey(userID(c), req.Name, req.Scope)
+	if err == auth.ErrInvalidScope {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	key.Hash = ""
+	return c.JSON(fiber.Map{
+		"key":    plain,
+		"apiKey": key,
+	})
+}
+
+func (s *Server) deleteKey(c *fiber.Ctx) error {
+	err := s.auth.DeleteKey(userID(c), c.Params("id"))
+	if err == auth.ErrKeyNotFound {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
@@ -0,0 +1,55 @@
+package web
+
+import (
+	"errors"
+	"log"
+
+	"shinkan-rebirth/internal/discovery"
+	"shinkan-rebirth/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// discoverFeeds lists the feeds found for a page URL
+func (s *Server) discoverFeeds(c *fiber.Ctx) error {
+	var req struct {
+		URL string `json:"url"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	if req.URL == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "URL required"})
+	}
+
+	candidates, err := s.discovery.Discover(s.ctx, req.URL)
+	if errors.Is(err, discovery.ErrNoFeed) {
+		return c.JSON(fiber.Map{"candidates": []interface{}{}})
+	}
+	if err != nil {
+		return c.Status(502).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"candidates": candidates})
+}
+
+// resolveFeedURL turns a page URL into the feed URL to store. Pages that
+// can't be fetched right now are stored as given, the checker reports them
+// later; ok is false if the page offers no feed at all. Only RSS sources
+// are looked up, other sources read the URL as it is.
+func (s *Server) resolveFeedURL(rssURL string, source *models.Source) (string, bool) {
+	if (models.Feed{Source: source}).SourceKind() != models.SourceRSS {
+		return rssURL, true
+	}
+
+	resolved, _, err := s.discovery.Resolve(s.ctx, rssURL)
+	if errors.Is(err, discovery.ErrNoFeed) {
+		return rssURL, false
+	}
+	if err != nil {
+		log.Printf("⚠️ Feed discovery for %s failed, storing it as given: %v\n", rssURL, err)
+	}
+	return resolved, true
+}
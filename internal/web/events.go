@@ -0,0 +1,149 @@
+package web
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"shinkan-rebirth/internal/events"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// clientEvent is what the browser receives for each SSE message
+type clientEvent struct {
+	Type string      `json:"type"`
+	Time string      `json:"time"`
+	Data interface{} `json:"data"`
+}
+
+// toClientEvents maps a bus event to the SSE events the web UI listens for.
+// Feed events are only sent to the feed's owner.
+func toClientEvents(event events.Event, userID string) []clientEvent {
+	now := time.Now().Format(time.RFC3339)
+
+	switch e := event.(type) {
+	case events.CheckStarted:
+		return []clientEvent{{Type: "check-started", Time: now, Data: e.Job}}
+	case events.CheckFinished:
+		return []clientEvent{{Type: "check-finished", Time: now, Data: e.Job}}
+	case events.FeedChecked:
+		if e.Feed.OwnerID != userID {
+			return nil
+		}
+		return []clientEvent{{Type: "feed-updated", Time: now, Data: e.Feed}}
+	case events.ReleaseDetected:
+		if e.Feed.OwnerID != userID {
+			return nil
+		}
+		data := fiber.Map{
+			"feedId":   e.Feed.ID,
+			"feedName": e.Feed.Name,
+			"type":     e.Feed.Type,
+			"title":    e.Title,
+			"link":     e.Link,
+			"chapter":  e.Info.Chapter,
+			"volume":   e.Info.Volume,
+			"group":    e.Info.Group,
+			"language": e.Info.Language,
+			"seriesId": e.Feed.SeriesID,
+		}
+		if e.Series != nil {
+			data["seriesTitle"] = e.Series.Title
+		}
+		return []clientEvent{{Type: "release-detected", Time: now, Data: data}}
+	case events.ReleaseDuplicate:
+		if e.Feed.OwnerID != userID {
+			return nil
+		}
+		return []clientEvent{{Type: "release-duplicate", Time: now, Data: fiber.Map{
+			"feedId":   e.Feed.ID,
+			"feedName": e.Feed.Name,
+			"seriesId": e.Feed.SeriesID,
+			"title":    e.Title,
+			"sources":  e.Sources,
+		}}}
+	case events.TorrentAdded:
+		if e.Feed.OwnerID != userID {
+			return nil
+		}
+		return []clientEvent{{Type: "torrent-added", Time: now, Data: fiber.Map{
+			"feedId":   e.Feed.ID,
+			"feedName": e.Feed.Name,
+			"client":   e.Client,
+			"title":    e.Title,
+			"error":    e.Error,
+		}}}
+	case events.FeedFailed:
+		if e.Feed.OwnerID != userID {
+			return nil
+		}
+		return []clientEvent{
+			{Type: "feed-updated", Time: now, Data: e.Feed},
+			{Type: "feed-error", Time: now, Data: fiber.Map{
+				"feedId":      e.Feed.ID,
+				"feedName":    e.Feed.Name,
+				"error":       e.Error,
+				"errorKind":   e.Kind,
+				"errorStatus": e.StatusCode,
+				"failCount":   e.Feed.FailCount,
+			}},
+		}
+	}
+
+	return nil
+}
+
+// streamEvents pushes bus events to the browser as Server-Sent Events
+func (s *Server) streamEvents(c *fiber.Ctx) error {
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	owner := userID(c)
+	sub, unsubscribe := s.events.Subscribe(32)
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+
+		heartbeat := time.NewTicker(15 * time.Second)
+		defer heartbeat.Stop()
+
+		// Tell the client we're connected so it can resync state
+		fmt.Fprint(w, "retry: 5000\n: connected\n\n")
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": ping\n\n")
+			case event, ok := <-sub:
+				if !ok {
+					return
+				}
+				for _, ce := range toClientEvents(event, owner) {
+					data, err := json.Marshal(ce)
+					if err != nil {
+						log.Printf("⚠️ Failed to encode event: %v\n", err)
+						continue
+					}
+					fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ce.Type, data)
+				}
+			}
+
+			// Flush fails once the client has gone away
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package web
+
+import (
+	"encoding/base64"
+
+	"shinkan-rebirth/internal/importer"
+	"shinkan-rebirth/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// previewImport runs one of the external importers and returns the feeds it
+// would create, without storing anything. The UI then sends the selected
+// feeds to /api/import.
+func (s *Server) previewImport(c *fiber.Ctx) error {
+	var req struct {
+		Source   string   `json:"source"` // "anilist", "mal" or "mangadex"
+		Username string   `json:"username"`
+		Type     string   `json:"type"`
+		Statuses []string `json:"statuses"`
+		Data     string   `json:"data"` // Pasted text
+		File     string   `json:"file"` // Uploaded file, base64 encoded
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	data := []byte(req.Data)
+	if req.File != "" {
+		decoded, err := base64.StdEncoding.DecodeString(req.File)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "File must be base64 encoded"})
+		}
+		data = decoded
+	}
+
+	var candidates []importer.Candidate
+	var err error
+
+	switch req.Source {
+	case "anilist":
+		feedType := models.FeedTypeManga
+		if req.Type == string(models.FeedTypeAnime) {
+			feedType = models.FeedTypeAnime
+		}
+		candidates, err = s.importer.FromAniList(s.ctx, req.Username, feedType, req.Statuses)
+	case "mal":
+		if len(data) == 0 {
+			return c.Status(400).JSON(fiber.Map{"error": "MyAnimeList export file required"})
+		}
+		candidates, err = s.importer.FromMAL(s.ctx, data, req.Statuses)
+	case "mangadex":
+		if len(data) == 0 {
+			return c.Status(400).JSON(fiber.Map{"error": "MangaDex follows required"})
+		}
+		candidates, err = s.importer.FromMangaDex(s.ctx, data)
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": "source must be anilist, mal or mangadex"})
+	}
+
+	if err != nil {
+		return c.Status(502).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// Flag feeds the user already has, ImportFeeds would skip them
+	feeds, err := s.storage.GetUserFeeds(userID(c))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	existing := make(map[string]bool)
+	for _, feed := range feeds {
+		existing[feed.RSSUrl] = true
+	}
+	for i := range candidates {
+		candidates[i].Exists = candidates[i].Feed.RSSUrl != "" && existing[candidates[i].Feed.RSSUrl]
+	}
+
+	return c.JSON(fiber.Map{"candidates": candidates})
+}
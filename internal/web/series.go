@@ -0,0 +1,257 @@
+package web
+
+import (
+	"strings"
+
+	"shinkan-rebirth/internal/checker"
+	"shinkan-rebirth/internal/models"
+	"shinkan-rebirth/internal/storage"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// seriesRequest is the body of creating or updating a series. The AniList
+// ID can be given as an AniList URL instead.
+type seriesRequest struct {
+	Title      string              `json:"title"`
+	Type       string              `json:"type"`
+	Aliases    []string            `json:"aliases"`
+	AnilistID  int                 `json:"anilistId"`
+	AnilistUrl string              `json:"anilistUrl"`
+	Cover      string              `json:"cover"`
+	Status     models.SeriesStatus `json:"status"`
+	Category   string              `json:"category"`
+	Progress   string              `json:"progress"`
+}
+
+// parse checks the request and fills in the AniList ID and status
+func (req *seriesRequest) parse() string {
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" {
+		return "Title required"
+	}
+	if req.Status == "" {
+		req.Status = models.SeriesCurrent
+	}
+	if !req.Status.Valid() {
+		return "status must be current, planning, paused, completed or dropped"
+	}
+	if req.AnilistUrl != "" {
+		req.AnilistID = models.ParseAnilistID(req.AnilistUrl)
+		if req.AnilistID == 0 {
+			return "Invalid AniList URL"
+		}
+	}
+
+	aliases := make([]string, 0, len(req.Aliases))
+	for _, alias := range req.Aliases {
+		if alias = strings.TrimSpace(alias); alias != "" {
+			aliases = append(aliases, alias)
+		}
+	}
+	req.Aliases = aliases
+	return ""
+}
+
+// seriesViews joins a user's series with their feeds
+func (s *Server) seriesViews(ownerID string) ([]models.SeriesView, error) {
+	series, err := s.storage.GetUserSeries(ownerID)
+	if err != nil {
+		return nil, err
+	}
+	feeds, err := s.storage.GetUserFeeds(ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]models.SeriesView, 0, len(series))
+	index := make(map[string]int, len(series))
+	for _, item := range series {
+		index[item.ID] = len(views)
+		views = append(views, models.SeriesView{Series: item, Feeds: []models.Feed{}})
+	}
+
+	for _, feed := range feeds {
+		i, ok := index[feed.SeriesID]
+		if !ok {
+			continue
+		}
+		view := &views[i]
+		view.Feeds = append(view.Feeds, feed)
+
+		if number := latestNumber(feed); number != "" && (view.Latest == "" || checker.CompareNumbers(number, view.Latest) > 0) {
+			view.Latest = number
+		}
+	}
+
+	for i := range views {
+		view := &views[i]
+		view.Unread = view.Latest != "" && view.Progress != "" && checker.CompareNumbers(view.Latest, view.Progress) > 0
+	}
+	return views, nil
+}
+
+// latestNumber is the number of the last release a feed saw
+func latestNumber(feed models.Feed) string {
+	if feed.LastEpisode != nil {
+		return *feed.LastEpisode
+	}
+	if feed.LastChapter != nil {
+		return checker.ReleaseNumber(*feed.LastChapter)
+	}
+	return ""
+}
+
+func (s *Server) seriesView(ownerID, id string) (*models.SeriesView, error) {
+	views, err := s.seriesViews(ownerID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range views {
+		if views[i].ID == id {
+			return &views[i], nil
+		}
+	}
+	return nil, storage.ErrSeriesNotFound
+}
+
+func (s *Server) getSeriesList(c *fiber.Ctx) error {
+	category := c.Query("category")
+	status := c.Query("status")
+
+	views, err := s.seriesViews(userID(c))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	filtered := make([]models.SeriesView, 0, len(views))
+	for _, view := range views {
+		if category != "" && category != "all" && view.Category != category {
+			continue
+		}
+		if status != "" && string(view.Status) != status {
+			continue
+		}
+		filtered = append(filtered, view)
+	}
+	return c.JSON(filtered)
+}
+
+func (s *Server) getSeries(c *fiber.Ctx) error {
+	view, err := s.seriesView(userID(c), c.Params("id"))
+	if err == storage.ErrSeriesNotFound {
+		return c.Status(404).JSON(fiber.Map{"error": "Series not found"})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(view)
+}
+
+// addSeries creates a series without feeds; feeds join it with its ID as
+// their seriesId
+func (s *Server) addSeries(c *fiber.Ctx) error {
+	var req seriesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if msg := req.parse(); msg != "" {
+		return c.Status(400).JSON(fiber.Map{"error": msg})
+	}
+	if req.Type == "" {
+		req.Type = string(models.FeedTypeManga)
+	}
+
+	series, err := s.storage.AddSeries(models.Series{
+		OwnerID:   userID(c),
+		Title:     req.Title,
+		Type:      models.FeedType(req.Type),
+		Aliases:   req.Aliases,
+		AnilistID: req.AnilistID,
+		Cover:     req.Cover,
+		Status:    req.Status,
+		Category:  req.Category,
+		Progress:  req.Progress,
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(series)
+}
+
+func (s *Server) updateSeries(c *fiber.Ctx) error {
+	var req seriesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if msg := req.parse(); msg != "" {
+		return c.Status(400).JSON(fiber.Map{"error": msg})
+	}
+
+	series, err := s.storage.UpdateSeries(userID(c), c.Params("id"), map[string]interface{}{
+		"title":     req.Title,
+		"aliases":   req.Aliases,
+		"anilistId": req.AnilistID,
+		"cover":     req.Cover,
+		"status":    req.Status,
+		"category":  req.Category,
+		"progress":  req.Progress,
+	})
+	if err == storage.ErrSeriesNotFound {
+		return c.Status(404).JSON(fiber.Map{"error": "Series not found"})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(series)
+}
+
+// markSeriesRead sets the read progress of a series, to its latest release
+// unless a progress is given
+func (s *Server) markSeriesRead(c *fiber.Ctx) error {
+	var req struct {
+		Progress string `json:"progress"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+
+	owner, id := userID(c), c.Params("id")
+	progress := strings.TrimSpace(req.Progress)
+	if progress == "" {
+		view, err := s.seriesView(owner, id)
+		if err == storage.ErrSeriesNotFound {
+			return c.Status(404).JSON(fiber.Map{"error": "Series not found"})
+		}
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if view.Latest == "" {
+			return c.Status(400).JSON(fiber.Map{"error": "No release of this series has a chapter or episode number yet"})
+		}
+		progress = view.Latest
+	}
+
+	series, err := s.storage.UpdateSeries(owner, id, map[string]interface{}{"progress": progress})
+	if err == storage.ErrSeriesNotFound {
+		return c.Status(404).JSON(fiber.Map{"error": "Series not found"})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(series)
+}
+
+// deleteSeries removes a series together with its feeds
+func (s *Server) deleteSeries(c *fiber.Ctx) error {
+	deleted, err := s.storage.DeleteSeries(userID(c), c.Params("id"))
+	if err == storage.ErrSeriesNotFound {
+		return c.Status(404).JSON(fiber.Map{"error": "Series not found"})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"success": true, "feedsDeleted": deleted})
+}
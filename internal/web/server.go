@@ -1,13 +1,26 @@
 package web
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"log"
+	"strconv"
 	"strings"
 	"time"
 
+	"shinkan-rebirth/internal/auth"
 	"shinkan-rebirth/internal/checker"
+	"shinkan-rebirth/internal/discovery"
+	"shinkan-rebirth/internal/events"
+	"shinkan-rebirth/internal/importer"
+	"shinkan-rebirth/internal/metrics"
+	"shinkan-rebirth/internal/migrations"
 	"shinkan-rebirth/internal/models"
+	"shinkan-rebirth/internal/opml"
+	"shinkan-rebirth/internal/stats"
 	"shinkan-rebirth/internal/storage"
+	"shinkan-rebirth/internal/torrent"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -15,25 +28,46 @@ import (
 )
 
 type Server struct {
+	ctx       context.Context
 	app       *fiber.App
 	storage   *storage.Storage
 	checker   *checker.Checker
+	stats     *stats.Collector
+	metrics   *metrics.Metrics
+	importer  *importer.Importer
+	discovery *discovery.Discoverer
+	handoff   *torrent.Handoff
+	events    *events.Bus
+	auth      *auth.Manager
 	startTime time.Time
 }
 
-func New(storage *storage.Storage, checker *checker.Checker, startTime time.Time) *Server {
+// New creates the web server. Checks started from the API run under ctx so
+// they are cancelled on shutdown.
+func New(ctx context.Context, storage *storage.Storage, checker *checker.Checker, collector *stats.Collector, metrics *metrics.Metrics, importer *importer.Importer, discoverer *discovery.Discoverer, handoff *torrent.Handoff, bus *events.Bus, authManager *auth.Manager, corsOrigins string, startTime time.Time) *Server {
 	app := fiber.New(fiber.Config{
 		DisableStartupMessage: true,
 	})
 
 	// Middleware
-	app.Use(cors.New())
+	app.Use(cors.New(cors.Config{
+		AllowOrigins: corsOrigins,
+		AllowHeaders: "Origin, Content-Type, Accept, Authorization, " + csrfHeader,
+	}))
 	app.Use(logger.New())
 
 	server := &Server{
+		ctx:       ctx,
 		app:       app,
 		storage:   storage,
 		checker:   checker,
+		stats:     collector,
+		metrics:   metrics,
+		importer:  importer,
+		discovery: discoverer,
+		handoff:   handoff,
+		events:    bus,
+		auth:      authManager,
 		startTime: startTime,
 	}
 
@@ -43,36 +77,74 @@ func New(storage *storage.Storage, checker *checker.Checker, startTime time.Time
 }
 
 func (s *Server) setupRoutes() {
-	// Static files
+	// Static files, the dashboard itself needs a login when auth is enabled
+	s.app.Use(s.requireLoginPage)
 	s.app.Static("/", "./public")
 
+	// Prometheus scrape endpoint, scrapers can use a read API key
+	s.app.Get("/metrics", s.authenticate, requireAdminUser, s.getMetrics)
+
 	// API routes
-	api := s.app.Group("/api")
+	api := s.app.Group("/api", s.authenticate)
+
+	api.Post("/auth/login", s.login)
+	api.Post("/auth/logout", s.logout)
+	api.Get("/auth/status", s.getAuthStatus)
+
+	keys := api.Group("/keys", requireAdmin)
+	keys.Get("/", s.getKeys)
+	keys.Post("/", s.createKey)
+	keys.Delete("/:id", s.deleteKey)
+
+	api.Get("/users/me", s.getCurrentUser)
+	api.Put("/users/me", requireAdmin, s.updateCurrentUser)
+
+	users := api.Group("/users", requireAdmin, requireAdminUser)
+	users.Get("/", s.getUsers)
+	users.Post("/", s.createUser)
+	users.Delete("/:id", s.deleteUser)
 
 	api.Get("/feeds", s.getFeeds)
 	api.Get("/categories", s.getCategories)
 	api.Get("/stats", s.getStats)
+	api.Get("/stats/history", s.getStatsHistory)
 	api.Get("/health", s.getHealth)
 	api.Post("/feeds", s.addFeed)
+	api.Post("/feeds/preview", s.previewFeed)
+	api.Post("/feeds/discover", s.discoverFeeds)
 	api.Post("/import", s.importFeeds)
+	api.Post("/import/opml", s.importOPML)
+	api.Post("/import/preview", s.previewImport)
 	api.Delete("/feeds/:id", s.deleteFeed)
 	api.Put("/feeds/:id", s.updateFeed)
 	api.Post("/feeds/:id/test", s.testFeed)
 	api.Post("/feeds/:id/check", s.checkFeed)
+	api.Get("/series", s.getSeriesList)
+	api.Post("/series", s.addSeries)
+	api.Get("/series/:id", s.getSeries)
+	api.Put("/series/:id", s.updateSeries)
+	api.Delete("/series/:id", s.deleteSeries)
+	api.Post("/series/:id/read", s.markSeriesRead)
+	api.Post("/check", s.startCheck)
+	api.Get("/check/status", s.getCheckStatus)
 	api.Get("/export", s.exportFeeds)
+	api.Get("/torrent/clients", s.getTorrentClients)
+	api.Get("/events", s.streamEvents)
 }
 
 func (s *Server) getFeeds(c *fiber.Ctx) error {
 	category := c.Query("category")
 	search := c.Query("search")
+	errorKind := c.Query("errorKind")
+	owner := userID(c)
 
-	feeds, err := s.storage.GetFeeds()
+	feeds, err := s.storage.GetUserFeeds(owner)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	if search != "" {
-		feeds, err = s.storage.SearchFeeds(search)
+		feeds, err = s.storage.SearchFeeds(owner, search)
 		if err != nil {
 			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 		}
@@ -90,11 +162,21 @@ func (s *Server) getFeeds(c *fiber.Ctx) error {
 		feeds = filtered
 	}
 
+	if errorKind != "" {
+		filtered := make([]models.Feed, 0)
+		for _, feed := range feeds {
+			if string(feed.ErrorKind) == errorKind {
+				filtered = append(filtered, feed)
+			}
+		}
+		feeds = filtered
+	}
+
 	return c.JSON(feeds)
 }
 
 func (s *Server) getCategories(c *fiber.Ctx) error {
-	categories, err := s.storage.GetCategories()
+	categories, err := s.storage.GetCategories(userID(c))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -102,39 +184,33 @@ func (s *Server) getCategories(c *fiber.Ctx) error {
 }
 
 func (s *Server) getStats(c *fiber.Ctx) error {
-	feeds, err := s.storage.GetFeeds()
+	feeds, err := s.storage.GetUserFeeds(userID(c))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	checkerStats := s.checker.GetStats()
-	categories, _ := s.storage.GetCategories()
+	summary := stats.Summarize(s.stats.Get(), feeds)
+	summary.Uptime = time.Since(s.startTime).Milliseconds()
 
-	feedsWithErrors := 0
-	feedsNeverChecked := 0
-	for _, feed := range feeds {
-		if feed.FailCount > 0 {
-			feedsWithErrors++
-		}
-		if feed.LastChecked == nil {
-			feedsNeverChecked++
-		}
-	}
+	return c.JSON(summary)
+}
 
-	stats := models.Stats{
-		TotalChecks:       checkerStats.TotalChecks,
-		SuccessfulChecks:  checkerStats.SuccessfulChecks,
-		FailedChecks:      checkerStats.FailedChecks,
-		NotificationsSent: checkerStats.NotificationsSent,
-		LastCheckTime:     checkerStats.LastCheckTime,
-		TotalFeeds:        len(feeds),
-		FeedsWithErrors:   feedsWithErrors,
-		FeedsNeverChecked: feedsNeverChecked,
-		Categories:        len(categories),
-		Uptime:            time.Since(s.startTime).Milliseconds(),
+// getStatsHistory returns daily rollups, e.g. ?range=30d for the last 30
+// days
+func (s *Server) getStatsHistory(c *fiber.Ctx) error {
+	days := 30
+	if r := c.Query("range"); r != "" {
+		n, err := strconv.Atoi(strings.TrimSuffix(r, "d"))
+		if err != nil || !strings.HasSuffix(r, "d") || n < 1 || n > 90 {
+			return c.Status(400).JSON(fiber.Map{"error": "range must be between 1d and 90d"})
+		}
+		days = n
 	}
 
-	return c.JSON(stats)
+	return c.JSON(fiber.Map{
+		"range": fmt.Sprintf("%dd", days),
+		"days":  s.stats.History(days),
+	})
 }
 
 func (s *Server) getHealth(c *fiber.Ctx) error {
@@ -145,14 +221,84 @@ func (s *Server) getHealth(c *fiber.Ctx) error {
 	})
 }
 
+// previewFeed fetches a URL before it is added and reports what the feed
+// contains and anything that looks wrong with it
+func (s *Server) previewFeed(c *fiber.Ctx) error {
+	var req struct {
+		RSSUrl     string         `json:"rssUrl"`
+		Type       string         `json:"type"`
+		SearchText string         `json:"searchText"`
+		Source     *models.Source `json:"source"`
+		Limit      int            `json:"limit"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	if req.RSSUrl == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "RSS URL required"})
+	}
+
+	if req.Type == "" {
+		req.Type = string(models.FeedTypeManga)
+	}
+
+	if req.Limit <= 0 || req.Limit > 50 {
+		req.Limit = 5
+	}
+
+	if err := checker.ValidateSource(req.Source); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	feed := models.Feed{
+		RSSUrl: req.RSSUrl,
+		Type:   models.FeedType(req.Type),
+		Source: req.Source,
+	}
+	if req.SearchText != "" {
+		feed.SearchText = &req.SearchText
+	}
+
+	// A page URL is previewed as the feed autodiscovery would pick
+	var candidates []models.DiscoveredFeed
+	if feed.SourceKind() == models.SourceRSS {
+		feed.RSSUrl, candidates, _ = s.discovery.Resolve(s.ctx, req.RSSUrl)
+	}
+	rssURL := feed.RSSUrl
+
+	preview := s.checker.PreviewFeed(s.ctx, feed, req.Limit)
+
+	if rssURL != req.RSSUrl {
+		preview.Candidates = candidates
+	}
+
+	feeds, err := s.storage.GetUserFeeds(userID(c))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	for _, feed := range feeds {
+		if feed.RSSUrl == rssURL {
+			preview.Warnings = append(preview.Warnings, fmt.Sprintf("You already follow this feed as %q", feed.Name))
+		}
+	}
+
+	return c.JSON(preview)
+}
+
 func (s *Server) addFeed(c *fiber.Ctx) error {
 	var req struct {
-		Name       string  `json:"name"`
-		RSSUrl     string  `json:"rssUrl"`
-		Type       string  `json:"type"`
-		AnilistUrl *string `json:"anilistUrl"`
-		Category   string  `json:"category"`
-		SearchText *string `json:"searchText"`
+		Name        string                     `json:"name"`
+		RSSUrl      string                     `json:"rssUrl"`
+		Type        string                     `json:"type"`
+		AnilistUrl  *string                    `json:"anilistUrl"`
+		Category    string                     `json:"category"`
+		SearchText  *string                    `json:"searchText"`
+		Source      *models.Source             `json:"source"`
+		Torrent     *models.TorrentHandoff     `json:"torrent"`
+		Preferences *models.ReleasePreferences `json:"preferences"`
+		SeriesID    string                     `json:"seriesId"`
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -168,18 +314,41 @@ func (s *Server) addFeed(c *fiber.Ctx) error {
 		req.Type = string(models.FeedTypeManga)
 	}
 
-	// Auto-append /rss if not present (for manga feeds)
-	if req.Type == string(models.FeedTypeManga) && !strings.HasSuffix(req.RSSUrl, "/rss") {
-		req.RSSUrl = strings.TrimSuffix(req.RSSUrl, "/") + "/rss"
+	if err := checker.ValidateSource(req.Source); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	if err := s.handoff.Validate(req.Torrent); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := checker.ValidatePreferences(req.Preferences); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	req.SeriesID = strings.TrimSpace(req.SeriesID)
+	if req.SeriesID != "" && !models.ValidSeriesID(req.SeriesID) {
+		return c.Status(400).JSON(fiber.Map{"error": "Series ID may only contain letters, digits, '.', '_' and '-'"})
+	}
+
+	rssURL, ok := s.resolveFeedURL(req.RSSUrl, req.Source)
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "No RSS or Atom feed found at this URL"})
+	}
+	req.RSSUrl = rssURL
+
 	feed := models.Feed{
-		Name:       req.Name,
-		RSSUrl:     req.RSSUrl,
-		Type:       models.FeedType(req.Type),
-		AnilistUrl: req.AnilistUrl,
-		Category:   req.Category,
-		SearchText: req.SearchText,
+		Name:        req.Name,
+		RSSUrl:      req.RSSUrl,
+		Type:        models.FeedType(req.Type),
+		AnilistUrl:  req.AnilistUrl,
+		Category:    req.Category,
+		SearchText:  req.SearchText,
+		OwnerID:     userID(c),
+		Source:      req.Source,
+		Torrent:     req.Torrent,
+		Preferences: req.Preferences,
+		SeriesID:    req.SeriesID,
 	}
 
 	newFeed, err := s.storage.AddFeed(feed)
@@ -190,12 +359,25 @@ func (s *Server) addFeed(c *fiber.Ctx) error {
 	return c.JSON(newFeed)
 }
 
+// importFeeds imports feeds from Shinkan's JSON export. Conflicts with
+// existing feeds are resolved per strategy; dryRun only reports the changes.
 func (s *Server) importFeeds(c *fiber.Ctx) error {
 	var req struct {
-		Feeds []models.Feed `json:"feeds"`
+		Feeds         []models.Feed                     `json:"feeds"`
+		Series        []models.Series                   `json:"series"`
+		Strategy      storage.ImportStrategy            `json:"strategy"`
+		Strategies    map[string]storage.ImportStrategy `json:"strategies"`
+		PreserveState bool                              `json:"preserveState"`
+		DryRun        bool                              `json:"dryRun"`
 	}
 
-	if err := c.BodyParser(&req); err != nil {
+	// Exports from older versions are upgraded like data files
+	body, _, err := migrations.MigrateImport(c.Body())
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := json.Unmarshal(body, &req); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
 	}
 
@@ -203,20 +385,95 @@ func (s *Server) importFeeds(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid import data"})
 	}
 
-	imported, skipped, err := s.storage.ImportFeeds(req.Feeds)
+	return s.runImport(c, req.Feeds, storage.ImportOptions{
+		Strategy:      req.Strategy,
+		Strategies:    req.Strategies,
+		PreserveState: req.PreserveState,
+		DryRun:        req.DryRun,
+		Series:        req.Series,
+	})
+}
+
+// importOPML imports feeds from an OPML document. The document is either
+// the raw request body, with the strategy, preserveState and dryRun options
+// as query parameters, or the "opml" field of a JSON body taking the same
+// options as importFeeds.
+func (s *Server) importOPML(c *fiber.Ctx) error {
+	var req struct {
+		OPML          string                            `json:"opml"`
+		Strategy      storage.ImportStrategy            `json:"strategy"`
+		Strategies    map[string]storage.ImportStrategy `json:"strategies"`
+		PreserveState bool                              `json:"preserveState"`
+		DryRun        bool                              `json:"dryRun"`
+	}
+
+	if c.Is("json") {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	} else {
+		req.OPML = string(c.Body())
+		req.Strategy = storage.ImportStrategy(c.Query("strategy"))
+		req.PreserveState = c.QueryBool("preserveState")
+		req.DryRun = c.QueryBool("dryRun")
+	}
+
+	feeds, err := opml.Parse(strings.NewReader(req.OPML))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return s.runImport(c, feeds, storage.ImportOptions{
+		Strategy:      req.Strategy,
+		Strategies:    req.Strategies,
+		PreserveState: req.PreserveState,
+		DryRun:        req.DryRun,
+	})
+}
+
+func (s *Server) runImport(c *fiber.Ctx, feeds []models.Feed, opts storage.ImportOptions) error {
+	if opts.Strategy != "" && !opts.Strategy.Valid() {
+		return c.Status(400).JSON(fiber.Map{"error": "strategy must be skip, overwrite, merge or keep_state"})
+	}
+	for _, strategy := range opts.Strategies {
+		if !strategy.Valid() {
+			return c.Status(400).JSON(fiber.Map{"error": "strategy must be skip, overwrite, merge or keep_state"})
+		}
+	}
+
+	opts.Validate = s.validateFeedSettings
+	report, err := s.storage.Import(userID(c), feeds, opts)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	return c.JSON(fiber.Map{
-		"imported": imported,
-		"skipped":  skipped,
+		"imported": report.Added,
+		"skipped":  report.Skipped,
+		"updated":  report.Overwritten + report.Merged + report.KeptState,
+		"report":   report,
 	})
 }
 
+// validateFeedSettings makes the checks of addFeed and updateFeed on an
+// imported feed
+func (s *Server) validateFeedSettings(feed models.Feed) error {
+	if err := checker.ValidateSource(feed.Source); err != nil {
+		return err
+	}
+	if err := s.handoff.Validate(feed.Torrent); err != nil {
+		return err
+	}
+	return checker.ValidatePreferences(feed.Preferences)
+}
+
 func (s *Server) deleteFeed(c *fiber.Ctx) error {
 	id := c.Params("id")
-	if err := s.storage.DeleteFeed(id); err != nil {
+	err := s.storage.DeleteFeed(userID(c), id)
+	if err == storage.ErrFeedNotFound {
+		return c.Status(404).JSON(fiber.Map{"error": "Feed not found"})
+	}
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 	return c.JSON(fiber.Map{"success": true})
@@ -226,12 +483,16 @@ func (s *Server) updateFeed(c *fiber.Ctx) error {
 	id := c.Params("id")
 
 	var req struct {
-		Name       string  `json:"name"`
-		RSSUrl     string  `json:"rssUrl"`
-		Type       string  `json:"type"`
-		AnilistUrl *string `json:"anilistUrl"`
-		Category   string  `json:"category"`
-		SearchText *string `json:"searchText"`
+		Name        string                     `json:"name"`
+		RSSUrl      string                     `json:"rssUrl"`
+		Type        string                     `json:"type"`
+		AnilistUrl  *string                    `json:"anilistUrl"`
+		Category    string                     `json:"category"`
+		SearchText  *string                    `json:"searchText"`
+		Source      *models.Source             `json:"source"`
+		Torrent     *models.TorrentHandoff     `json:"torrent"`
+		Preferences *models.ReleasePreferences `json:"preferences"`
+		SeriesID    string                     `json:"seriesId"`
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -242,17 +503,47 @@ func (s *Server) updateFeed(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Name and RSS URL required"})
 	}
 
-	// Auto-append /rss if not present (for manga feeds)
-	if req.Type == string(models.FeedTypeManga) && !strings.HasSuffix(req.RSSUrl, "/rss") {
-		req.RSSUrl = strings.TrimSuffix(req.RSSUrl, "/") + "/rss"
+	existing, err := s.storage.GetUserFeed(userID(c), id)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "Feed not found"})
+	}
+
+	if err := checker.ValidateSource(req.Source); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := s.handoff.Validate(req.Torrent); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := checker.ValidatePreferences(req.Preferences); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	req.SeriesID = strings.TrimSpace(req.SeriesID)
+	if req.SeriesID != "" && !models.ValidSeriesID(req.SeriesID) {
+		return c.Status(400).JSON(fiber.Map{"error": "Series ID may only contain letters, digits, '.', '_' and '-'"})
+	}
+
+	// Only a changed URL is looked up again
+	if req.RSSUrl != existing.RSSUrl {
+		rssURL, ok := s.resolveFeedURL(req.RSSUrl, req.Source)
+		if !ok {
+			return c.Status(400).JSON(fiber.Map{"error": "No RSS or Atom feed found at this URL"})
+		}
+		req.RSSUrl = rssURL
 	}
 
 	updates := map[string]interface{}{
-		"name":       req.Name,
-		"rssUrl":     req.RSSUrl,
-		"type":       req.Type,
-		"category":   req.Category,
-		"searchText": req.SearchText,
+		"name":        req.Name,
+		"rssUrl":      req.RSSUrl,
+		"type":        req.Type,
+		"category":    req.Category,
+		"searchText":  req.SearchText,
+		"source":      req.Source,
+		"torrent":     req.Torrent,
+		"preferences": req.Preferences,
+		"seriesId":    req.SeriesID,
 	}
 
 	if req.AnilistUrl != nil {
@@ -270,7 +561,7 @@ func (s *Server) updateFeed(c *fiber.Ctx) error {
 func (s *Server) testFeed(c *fiber.Ctx) error {
 	id := c.Params("id")
 
-	result, err := s.checker.TestFeed(id)
+	result, err := s.checker.TestFeed(s.ctx, userID(c), id)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -280,35 +571,32 @@ func (s *Server) testFeed(c *fiber.Ctx) error {
 
 func (s *Server) checkFeed(c *fiber.Ctx) error {
 	id := c.Params("id")
+	owner := userID(c)
 
-	feeds, err := s.storage.GetFeeds()
+	feed, err := s.storage.GetUserFeed(owner, id)
+	if err == storage.ErrFeedNotFound {
+		return c.Status(404).JSON(fiber.Map{"error": "Feed not found"})
+	}
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	var feed *models.Feed
-	for _, f := range feeds {
-		if f.ID == id {
-			feed = &f
-			break
+	// ?dryRun=true reports what the check would do without changing anything
+	if c.QueryBool("dryRun") {
+		preview, err := s.checker.CheckFeedDryRun(s.ctx, *feed, 3)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 		}
+		return c.JSON(preview)
 	}
 
-	if feed == nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Feed not found"})
-	}
-
-	if err := s.checker.CheckFeed(*feed, 3); err != nil {
+	if err := s.checker.CheckOne(s.ctx, feed.ID, checker.TriggerAPI); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	// Get updated feed
-	feeds, _ = s.storage.GetFeeds()
-	for _, f := range feeds {
-		if f.ID == id {
-			feed = &f
-			break
-		}
+	if updated, err := s.storage.GetUserFeed(owner, id); err == nil {
+		feed = updated
 	}
 
 	return c.JSON(fiber.Map{
@@ -318,17 +606,68 @@ func (s *Server) checkFeed(c *fiber.Ctx) error {
 	})
 }
 
+func (s *Server) startCheck(c *fiber.Ctx) error {
+	// A dry run of the user's feeds runs synchronously, outside the check job
+	if c.QueryBool("dryRun") {
+		feeds, err := s.storage.GetUserFeeds(userID(c))
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		previews, err := s.checker.CheckAllDryRun(s.ctx, feeds)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"dryRun": true, "previews": previews})
+	}
+
+	job, started := s.checker.StartCheckAll(s.ctx, checker.TriggerAPI)
+
+	return c.Status(202).JSON(fiber.Map{
+		"jobId":   job.ID,
+		"started": started,
+		"job":     job,
+	})
+}
+
+func (s *Server) getCheckStatus(c *fiber.Ctx) error {
+	job, ok := s.checker.CheckStatus()
+	if !ok {
+		return c.JSON(fiber.Map{"running": false, "job": nil})
+	}
+
+	return c.JSON(fiber.Map{
+		"running": job.Status == models.JobRunning,
+		"job":     job,
+	})
+}
+
+// getTorrentClients lists the torrent clients feeds can hand releases to
+func (s *Server) getTorrentClients(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"clients": s.handoff.Clients()})
+}
+
 func (s *Server) exportFeeds(c *fiber.Ctx) error {
-	feeds, err := s.storage.GetFeeds()
+	feeds, err := s.storage.GetUserFeeds(userID(c))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+	series, err := s.storage.GetUserSeries(userID(c))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if c.Query("format") == "opml" {
+		c.Set("Content-Disposition", "attachment; filename=shinkan-rebirth-export.opml")
+		c.Set("Content-Type", "text/x-opml; charset=utf-8")
+		return opml.Write(c, "Shinkan Rebirth feeds", feeds)
+	}
 
 	exportData := fiber.Map{
 		"exported": time.Now().Format(time.RFC3339),
-		"version":  "1.0",
+		"version":  models.SchemaVersion,
 		"count":    len(feeds),
 		"feeds":    feeds,
+		"series":   series,
 	}
 
 	c.Set("Content-Disposition", "attachment; filename=shinkan-rebirth-export.json")
@@ -337,6 +676,17 @@ func (s *Server) exportFeeds(c *fiber.Ctx) error {
 	return c.JSON(exportData)
 }
 
+func (s *Server) getMetrics(c *fiber.Ctx) error {
+	feeds, err := s.storage.GetFeeds()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	c.Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	s.metrics.Write(c, feeds)
+	return nil
+}
+
 func (s *Server) Start(port string) error {
 	if !strings.HasPrefix(port, ":") {
 		port = ":" + port
@@ -344,3 +694,9 @@ func (s *Server) Start(port string) error {
 	log.Printf("🌐 Web UI running at http://localhost%s\n", port)
 	return s.app.Listen(port)
 }
+
+// Shutdown stops accepting connections and waits up to timeout for open
+// requests to finish
+func (s *Server) Shutdown(timeout time.Duration) error {
+	return s.app.ShutdownWithTimeout(timeout)
+}
@@ -0,0 +1,106 @@
+package web
+
+import (
+	"shinkan-rebirth/internal/auth"
+	"shinkan-rebirth/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (s *Server) getCurrentUser(c *fiber.Ctx) error {
+	user, err := s.auth.GetUser(userID(c))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	user.PasswordHash = ""
+	return c.JSON(user)
+}
+
+// updateCurrentUser lets users change their own password and notification
+// targets. Only admins can point notifications at a Gotify server.
+func (s *Server) updateCurrentUser(c *fiber.Ctx) error {
+	var req struct {
+		Password *string                     `json:"password"`
+		Targets  *models.NotificationTargets `json:"targets"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	user, err := s.auth.UpdateUser(userID(c), req.Password, req.Targets)
+	if err == auth.ErrUserNotFound {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err == auth.ErrGotifyServer {
+		return c.Status(403).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	user.PasswordHash = ""
+	return c.JSON(user)
+}
+
+func (s *Server) getUsers(c *fiber.Ctx) error {
+	users, err := s.auth.ListUsers()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	for i := range users {
+		users[i].PasswordHash = ""
+	}
+	return c.JSON(users)
+}
+
+func (s *Server) createUser(c *fiber.Ctx) error {
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		IsAdmin  bool   `json:"isAdmin"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	user, err := s.auth.CreateUser(req.Username, req.Password, req.IsAdmin)
+	if err == auth.ErrInvalidUser {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err == auth.ErrUserExists {
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	user.PasswordHash = ""
+	return c.JSON(user)
+}
+
+// deleteUser removes an account together with its feeds
+func (s *Server) deleteUser(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	err := s.auth.DeleteUser(id)
+	if err == auth.ErrBuiltinUser {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err == auth.ErrUserNotFound {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	deleted, err := s.storage.DeleteUserFeeds(id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"success": true, "feedsDeleted": deleted})
+}